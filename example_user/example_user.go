@@ -3,7 +3,6 @@ package example_user
 import (
 	"errors"
 	"net/url"
-	"strconv"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/okayawright/exp_http_client/resources"
@@ -112,6 +111,12 @@ func Fetch(host string, id string) (*Data, int, error) {
 	return dataUserStruct, statusCode, err
 }
 
+/* URL parameters of a deletion */
+type deleteParameters struct {
+	ID      string `url:"user_id"`
+	Version int    `url:"version"`
+}
+
 /* Delete an existing User resource identified by the specified identifier and version, on the given host */
 func Delete(host string, id string, version int) (*Data, int, error) {
 
@@ -119,9 +124,9 @@ func Delete(host string, id string, version int) (*Data, int, error) {
 	res := resources.NewResource(mergeUrlHost(host, "http://localhost:8080/v1/membership/users/{user_id}?version={version}"))
 
 	//We do not need to cancel the request here so we can go straight to execute call() after Prepare()
-	call, _, err := res.Request("DELETE", &map[string]string{
-		"user_id": id,
-		"version": strconv.Itoa(version),
+	call, _, err := res.Request("DELETE", &deleteParameters{
+		ID:      id,
+		Version: version,
 	}, nil)
 	if err != nil {
 		return nil, 0, err
@@ -1,5 +1,5 @@
 module github.com/okayawright/exp_http_client
 
-go 1.16
+go 1.21
 
 require github.com/mitchellh/mapstructure v1.4.2
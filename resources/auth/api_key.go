@@ -0,0 +1,43 @@
+package auth
+
+import "net/http"
+
+/* API key authentication, sent either as a header or as a querystring parameter */
+type apiKey struct {
+	//Name of the header, or of the querystring parameter
+	name string
+	//The actual key
+	value string
+	//Send the key in the querystring rather than in a header
+	inQuery bool
+}
+
+/* apiKey c'tor.
+By default the key is sent as a header named name */
+func NewApiKey(name string, value string) *apiKey {
+	return &apiKey{
+		name:  name,
+		value: value,
+	}
+}
+
+/* Send the key as a querystring parameter instead of a header.
+Returns the updated authenticator */
+func (authenticator *apiKey) InQuery() *apiKey {
+	authenticator.inQuery = true
+	return authenticator
+}
+
+func (authenticator *apiKey) Authenticate(request *http.Request) error {
+	if authenticator.inQuery {
+		//Never modify the URL by side-effect, it may be shared with the original request
+		url := *request.URL
+		q := url.Query()
+		q.Set(authenticator.name, authenticator.value)
+		url.RawQuery = q.Encode()
+		request.URL = &url
+	} else {
+		request.Header.Set(authenticator.name, authenticator.value)
+	}
+	return nil
+}
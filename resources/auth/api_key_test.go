@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+/* Nominal case, the key is sent as a header */
+func TestApiKeyHeaderNominal(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://nowhere/info?withPhoto=true", nil)
+	if err := NewApiKey("X-Api-Key", "zufeb5e1b6e1b6eb").Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() unexpected error %v", err)
+	}
+	if observed := req.Header.Get("X-Api-Key"); observed != "zufeb5e1b6e1b6eb" {
+		t.Errorf("Authenticate() = %v, want %v", observed, "zufeb5e1b6e1b6eb")
+	}
+}
+
+/* Nominal case, the key is sent in the querystring without altering the original URL */
+func TestApiKeyQueryNominal(t *testing.T) {
+	expected := "http://nowhere/info?api_key=zufeb5e1b6e1b6eb&withPhoto=true"
+	req, _ := http.NewRequest("GET", "http://nowhere/info?withPhoto=true", nil)
+	original := req.URL
+	if err := NewApiKey("api_key", "zufeb5e1b6e1b6eb").InQuery().Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() unexpected error %v", err)
+	}
+	if req.URL.String() != expected {
+		t.Errorf("Authenticate() = %v, want %v", req.URL.String(), expected)
+	}
+	if original.String() != "http://nowhere/info?withPhoto=true" {
+		t.Errorf("Authenticate() modified the original URL %v", original.String())
+	}
+}
@@ -0,0 +1,15 @@
+package auth
+
+import "net/http"
+
+/* Add credentials to an outgoing request */
+type Authenticator interface {
+	//Augment the request with credentials, the request is never shared so it can be modified by side-effect
+	Authenticate(request *http.Request) error
+}
+
+/* Authenticator whose credentials can be invalidated, e.g. when the server rejects them with a 401 */
+type Invalidator interface {
+	//Forget the credentials the request was rejected with, unless they were already renewed, so that the next authentication gets fresh ones
+	Invalidate(rejected *http.Request)
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "net/http"
+
+/* Basic authentication, see RFC 7617 */
+type basic struct {
+	username string
+	password string
+}
+
+/* basic c'tor */
+func NewBasic(username string, password string) *basic {
+	return &basic{
+		username: username,
+		password: password,
+	}
+}
+
+func (authenticator *basic) Authenticate(request *http.Request) error {
+	request.SetBasicAuth(authenticator.username, authenticator.password)
+	return nil
+}
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+/* Nominal case, the credentials are sent with basic authentication */
+func TestBasicNominal(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	if err := NewBasic("julien", "s3cr3t").Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() unexpected error %v", err)
+	}
+	username, password, ok := req.BasicAuth()
+	if !ok || username != "julien" || password != "s3cr3t" {
+		t.Errorf("Authenticate() = %v:%v, want %v:%v", username, password, "julien", "s3cr3t")
+	}
+}
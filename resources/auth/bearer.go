@@ -0,0 +1,21 @@
+package auth
+
+import "net/http"
+
+/* Static bearer token authentication, see RFC 6750 */
+type bearer struct {
+	//Opaque access token
+	token string
+}
+
+/* bearer c'tor */
+func NewBearer(token string) *bearer {
+	return &bearer{
+		token: token,
+	}
+}
+
+func (authenticator *bearer) Authenticate(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+authenticator.token)
+	return nil
+}
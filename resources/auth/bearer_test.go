@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+/* Nominal case, the token is sent as a bearer */
+func TestBearerNominal(t *testing.T) {
+	expected := "Bearer zufeb5e1b6e1b6eb"
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	if err := NewBearer("zufeb5e1b6e1b6eb").Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() unexpected error %v", err)
+	}
+	if observed := req.Header.Get("Authorization"); observed != expected {
+		t.Errorf("Authenticate() = %v, want %v", observed, expected)
+	}
+}
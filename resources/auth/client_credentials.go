@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+// default margin before the actual expiry of a token from which it is refreshed
+const defaultExpiryMargin = 30 * time.Second
+
+/* OAuth2 client credentials grant, see RFC 6749 section 4.4.
+The access token is cached and shared by all the requests until it is about to expire */
+type clientCredentials struct {
+	//HTTP client used to request the tokens
+	client misc.HttpClient
+	//Authorization server token endpoint
+	tokenUrl *netUrl.URL
+	//Client identifier and secret issued by the authorization server
+	clientId     string
+	clientSecret string
+	//Optional requested scopes
+	scopes []string
+	//Refresh the token this long before it actually expires
+	expiryMargin time.Duration
+	//Cached token
+	token string
+	//Expiry of the cached token, zero means it never expires
+	expiry time.Time
+	//Protect the cached token
+	mutex sync.Mutex
+	//Time source
+	now func() time.Time
+}
+
+/* Token endpoint response */
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+/* clientCredentials c'tor.
+tokenUrl is the token endpoint of the authorization server, the client identifier and secret are sent to it with basic authentication */
+func NewClientCredentials(tokenUrl *netUrl.URL, clientId string, clientSecret string) *clientCredentials {
+	return &clientCredentials{
+		client:       http.DefaultClient,
+		tokenUrl:     tokenUrl,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		expiryMargin: defaultExpiryMargin,
+		now:          time.Now,
+	}
+}
+
+/* Request specific scopes.
+Returns the updated authenticator */
+func (authenticator *clientCredentials) WithScopes(scopes ...string) *clientCredentials {
+	authenticator.scopes = scopes
+	return authenticator
+}
+
+/* Use a specific HTTP client to request the tokens.
+Returns the updated authenticator */
+func (authenticator *clientCredentials) WithClient(client misc.HttpClient) *clientCredentials {
+	if client != nil {
+		authenticator.client = client
+	}
+	return authenticator
+}
+
+/* Refresh the token this long before it actually expires.
+Returns the updated authenticator */
+func (authenticator *clientCredentials) WithExpiryMargin(expiryMargin time.Duration) *clientCredentials {
+	authenticator.expiryMargin = expiryMargin
+	return authenticator
+}
+
+func (authenticator *clientCredentials) Authenticate(request *http.Request) error {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+
+	if len(authenticator.token) == 0 || (!authenticator.expiry.IsZero() && !authenticator.now().Before(authenticator.expiry.Add(-authenticator.expiryMargin))) {
+		if err := authenticator.fetchToken(request); err != nil {
+			return err
+		}
+	}
+	request.Header.Set("Authorization", "Bearer "+authenticator.token)
+	return nil
+}
+
+/* Forget the cached token if it is the one the request was rejected with.
+The concurrent requests rejected with the same token thus trigger a single refresh */
+func (authenticator *clientCredentials) Invalidate(rejected *http.Request) {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	if rejected.Header.Get("Authorization") == "Bearer "+authenticator.token {
+		authenticator.token = ""
+	}
+}
+
+/* Get a new token from the authorization server, within the context of the request to authenticate */
+func (authenticator *clientCredentials) fetchToken(request *http.Request) error {
+	form := netUrl.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(authenticator.scopes) > 0 {
+		form.Set("scope", strings.Join(authenticator.scopes, " "))
+	}
+	tokenRequest, err := http.NewRequestWithContext(request.Context(), "POST", authenticator.tokenUrl.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	tokenRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenRequest.Header.Set("Accept", "application/json")
+	tokenRequest.SetBasicAuth(netUrl.QueryEscape(authenticator.clientId), netUrl.QueryEscape(authenticator.clientSecret))
+
+	response, err := authenticator.client.Do(tokenRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	rawBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Cannot get an access token: %d %s", response.StatusCode, strings.TrimSpace(string(rawBody)))
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(rawBody, &token); err != nil {
+		return err
+	}
+	if len(token.AccessToken) == 0 {
+		return errors.New("Cannot get an access token: empty token")
+	}
+	if len(token.TokenType) > 0 && !strings.EqualFold(token.TokenType, "bearer") {
+		return fmt.Errorf("Cannot get an access token: unsupported token type %s", token.TokenType)
+	}
+	authenticator.token = token.AccessToken
+	if token.ExpiresIn > 0 {
+		authenticator.expiry = authenticator.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	} else {
+		authenticator.expiry = time.Time{}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Mock authorization server delivering numbered tokens */
+func mockTokenServer(t *testing.T, issued *int, expiresIn string) *mocks.Client {
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		clientId, clientSecret, _ := req.BasicAuth()
+		if clientId != "client" || clientSecret != "secret" {
+			t.Errorf("fetchToken() credentials = %v:%v", clientId, clientSecret)
+		}
+		req.ParseForm()
+		if req.PostForm.Get("grant_type") != "client_credentials" || req.PostForm.Get("scope") != "read write" {
+			t.Errorf("fetchToken() form = %v", req.PostForm)
+		}
+		*issued++
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"access_token":"token` + strconv.Itoa(*issued) + `","token_type":"Bearer","expires_in":` + expiresIn + `}`)),
+		}, nil
+	}
+	return &mockClient
+}
+
+/* Nominal case, the token is cached then refreshed before it expires */
+func TestClientCredentialsNominal(t *testing.T) {
+	issued := 0
+	tokenUrl, _ := netUrl.Parse("http://auth/token")
+	now := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	authenticator := NewClientCredentials(tokenUrl, "client", "secret").WithScopes("read", "write").WithClient(mockTokenServer(t, &issued, "3600"))
+	authenticator.now = func() time.Time { return now }
+
+	for _, expected := range []string{"Bearer token1", "Bearer token1"} {
+		req, _ := http.NewRequest("GET", "http://nowhere", nil)
+		if err := authenticator.Authenticate(req); err != nil {
+			t.Fatalf("Authenticate() unexpected error %v", err)
+		}
+		if observed := req.Header.Get("Authorization"); observed != expected {
+			t.Errorf("Authenticate() = %v, want %v", observed, expected)
+		}
+	}
+
+	//Within the expiry margin
+	now = now.Add(3600*time.Second - defaultExpiryMargin)
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	authenticator.Authenticate(req)
+	if observed := req.Header.Get("Authorization"); observed != "Bearer token2" {
+		t.Errorf("Authenticate() = %v, want %v", observed, "Bearer token2")
+	}
+	if issued != 2 {
+		t.Errorf("Authenticate() issued tokens = %v, want %v", issued, 2)
+	}
+}
+
+/* Nominal case, an invalidated token is renewed */
+func TestClientCredentialsInvalidateNominal(t *testing.T) {
+	issued := 0
+	tokenUrl, _ := netUrl.Parse("http://auth/token")
+	authenticator := NewClientCredentials(tokenUrl, "client", "secret").WithScopes("read", "write").WithClient(mockTokenServer(t, &issued, "0"))
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	authenticator.Authenticate(req)
+	authenticator.Invalidate(req)
+	authenticator.Authenticate(req)
+	if observed := req.Header.Get("Authorization"); observed != "Bearer token2" {
+		t.Errorf("Authenticate() = %v, want %v", observed, "Bearer token2")
+	}
+}
+
+/* Nominal case, concurrent requests rejected with the same token only trigger a single refresh */
+func TestClientCredentialsInvalidateConcurrentNominal(t *testing.T) {
+	issued := 0
+	tokenUrl, _ := netUrl.Parse("http://auth/token")
+	authenticator := NewClientCredentials(tokenUrl, "client", "secret").WithScopes("read", "write").WithClient(mockTokenServer(t, &issued, "0"))
+
+	var rejected []*http.Request
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "http://nowhere", nil)
+		authenticator.Authenticate(req)
+		rejected = append(rejected, req)
+	}
+	var wg sync.WaitGroup
+	for _, req := range rejected {
+		wg.Add(1)
+		go func(req *http.Request) {
+			defer wg.Done()
+			authenticator.Invalidate(req)
+			authenticator.Authenticate(req.Clone(req.Context()))
+		}(req)
+	}
+	wg.Wait()
+	if issued != 2 {
+		t.Errorf("Authenticate() issued tokens = %v, want %v", issued, 2)
+	}
+}
+
+/* Error case, the authorization server rejects the client */
+func TestClientCredentialsRejectedError(t *testing.T) {
+	tokenUrl, _ := netUrl.Parse("http://auth/token")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 401,
+			Body:       io.NopCloser(strings.NewReader(`{"error":"invalid_client"}`)),
+		}, nil
+	}
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	if err := NewClientCredentials(tokenUrl, "client", "secret").WithClient(&mockClient).Authenticate(req); err == nil {
+		t.Errorf("Authenticate() unexpected success")
+	}
+}
@@ -0,0 +1,66 @@
+package resources
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources/auth"
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+/* Authenticate every request sent by this resource, retries included.
+Returns the updated resource */
+func (resource *resource) WithAuth(authenticator auth.Authenticator) *resource {
+	resource.authenticator = authenticator
+	return resource
+}
+
+/* Add the credentials to every attempt.
+If the server rejects them with a 401 and they can be invalidated, they are renewed and the attempt is made again, once */
+func authenticate(authenticator auth.Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(exchange *Exchange) error {
+			original := exchange.Request
+			request, err := authenticatedClone(authenticator, original)
+			if err != nil {
+				return err
+			}
+			exchange.Request = request
+			err = next(exchange)
+			if err != nil || exchange.Response.StatusCode != http.StatusUnauthorized {
+				return err
+			}
+
+			invalidator, ok := authenticator.(auth.Invalidator)
+			if !ok {
+				return nil
+			}
+			rejected := request
+			//We cannot send a consumed body twice, stick with the 401
+			request, err = misc.Replay(original, false)
+			if errors.Is(err, misc.ErrBodyNotReplayable) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			invalidator.Invalidate(rejected)
+			if err = authenticator.Authenticate(request); err != nil {
+				return err
+			}
+			misc.Discard(exchange.Response)
+			exchange.Request = request
+			exchange.Response = nil
+			return next(exchange)
+		}
+	}
+}
+
+/* Clone the request, as it may be shared, and add the credentials to it.
+Returns the authenticated request */
+func authenticatedClone(authenticator auth.Authenticator, request *http.Request) (*http.Request, error) {
+	clone := request.Clone(request.Context())
+	if err := authenticator.Authenticate(clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
@@ -0,0 +1,120 @@
+package resources
+
+import (
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/okayawright/exp_http_client/resources/auth"
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Authenticator delivering a new token every time it is invalidated */
+type rotatingAuthenticator struct {
+	generation int
+}
+
+func (authenticator *rotatingAuthenticator) Authenticate(request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer token"+strconv.Itoa(authenticator.generation))
+	return nil
+}
+
+func (authenticator *rotatingAuthenticator) Invalidate(rejected *http.Request) {
+	if rejected.Header.Get("Authorization") == "Bearer token"+strconv.Itoa(authenticator.generation) {
+		authenticator.generation++
+	}
+}
+
+/* Nominal case, the credentials are added to every retry */
+func TestWithAuthRetryNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		if observed := req.Header.Get("Authorization"); observed != "Bearer zufeb5e1b6e1b6eb" {
+			t.Errorf("WithAuth() attempt %v = %v", pass, observed)
+		}
+		statusCode := 200
+		if pass == 1 {
+			statusCode = 503
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).WithAuth(auth.NewBearer("zufeb5e1b6e1b6eb"))
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if pass != 2 {
+		t.Errorf("Call() attempts = %v, want %v", pass, 2)
+	}
+}
+
+/* Nominal case, a 401 triggers a single re-authentication, with the same body */
+func TestWithAuthReauthenticateNominal(t *testing.T) {
+	expectedBody := `{"token":"zufeb5e1b6e1b6eb"}`
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		rawBody, _ := io.ReadAll(req.Body)
+		if string(rawBody) != expectedBody {
+			t.Errorf("WithAuth() attempt %v body = %v, want %v", pass, string(rawBody), expectedBody)
+		}
+		statusCode := 401
+		if req.Header.Get("Authorization") == "Bearer token1" {
+			statusCode = 201
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithAuth(&rotatingAuthenticator{})
+
+	call, _, _ := res.Request("POST", nil, map[string]string{"token": "zufeb5e1b6e1b6eb"})
+	_, observedStatusCode, err := call()
+	if err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if observedStatusCode != 201 {
+		t.Errorf("Call() = %v, want %v", observedStatusCode, 201)
+	}
+	if pass != 2 {
+		t.Errorf("Call() attempts = %v, want %v", pass, 2)
+	}
+}
+
+/* Error case, the renewed credentials are rejected again, no infinite loop */
+func TestWithAuthReauthenticateError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		return &http.Response{
+			StatusCode: 401,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithAuth(&rotatingAuthenticator{})
+
+	call, _, _ := res.Request("GET", nil, nil)
+	_, observedStatusCode, _ := call()
+	if observedStatusCode != 401 {
+		t.Errorf("Call() = %v, want %v", observedStatusCode, 401)
+	}
+	if pass != 2 {
+		t.Errorf("Call() attempts = %v, want %v", pass, 2)
+	}
+}
@@ -0,0 +1,110 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+// Sentinel errors, to be used with errors.Is()
+var (
+	//The response, or the request, cannot be (de)serialized with the available marshaller
+	ErrUnsupportedMediaType = errors.New("Cannot deserialize the response")
+	//The response body is larger than the maximum size allowed by the resource
+	ErrResponseTooLarge = errors.New("The response is too large")
+	//The response body is streamed, or the connection upgraded, it cannot be decoded as a whole
+	ErrStreamedResponse = errors.New("The response is streamed")
+	//HTTP 400
+	ErrBadRequest = errors.New("Bad request")
+	//HTTP 401
+	ErrUnauthorized = errors.New("Unauthorized")
+	//HTTP 403
+	ErrForbidden = errors.New("Forbidden")
+	//HTTP 404
+	ErrNotFound = errors.New("Not found")
+	//HTTP 409
+	ErrConflict = errors.New("Conflict")
+	//HTTP 415, the server cannot read the request body, unlike ErrUnsupportedMediaType
+	ErrUnsupportedMediaTypeStatus = errors.New("Unsupported media type")
+	//HTTP 429
+	ErrRateLimited = errors.New("Rate limited")
+	//HTTP 5xx
+	ErrServerError = errors.New("Server error")
+)
+
+/* An unsuccessful HTTP response, i.e. with a non-2xx status code */
+type HTTPError struct {
+	//HTTP status code of the response
+	StatusCode int
+	//Headers of the response
+	Header http.Header
+	//Raw body of the response
+	Body []byte
+	//Body of the response decoded by the resource marshaller, nil if empty or undecodable
+	Payload interface{}
+	//HTTP verb of the request
+	Method string
+	//Actual URL of the request, its password redacted
+	URL string
+	//Number of times the request has been sent again before giving up
+	Retries uint
+}
+
+/* HTTPError c'tor.
+The error payload is decoded on a best-effort basis, the raw body being always available */
+func newHTTPError(request *http.Request, response *http.Response, rawBody []byte, tries uint, marshaller serializers.Marshaller) *HTTPError {
+	err := &HTTPError{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       rawBody,
+		Method:     request.Method,
+		URL:        request.URL.Redacted(),
+	}
+	if tries > 0 {
+		err.Retries = tries - 1
+	}
+	if len(rawBody) > 0 {
+		//Pick the marshaller matching the error payload, if any
+		if decoder, decodeErr := serializers.Decoder(marshaller, response.Header.Get("Content-Type")); decodeErr == nil {
+			marshaller = decoder
+		}
+		if payload, decodeErr := marshaller.Deserialize(rawBody); decodeErr == nil {
+			err.Payload = payload
+		}
+	}
+	return err
+}
+
+func (err *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", err.Method, err.URL, err.StatusCode, http.StatusText(err.StatusCode))
+}
+
+/* Match the HTTP status code against the sentinel errors */
+func (err *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return err.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return err.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return err.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return err.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return err.StatusCode == http.StatusConflict
+	case ErrUnsupportedMediaTypeStatus:
+		return err.StatusCode == http.StatusUnsupportedMediaType
+	case ErrRateLimited:
+		return err.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return err.StatusCode >= 500 && err.StatusCode < 600
+	}
+	return false
+}
+
+/* Is the HTTP status code a 2xx one */
+func isSuccessful(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
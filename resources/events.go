@@ -0,0 +1,249 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+// default delay before reconnecting to an event stream
+const defaultReconnectionDelay = 3 * time.Second
+
+// longest delay before reconnecting to an event stream that cannot be reached
+const maxReconnectionDelay = 30 * time.Second
+
+// media type of the event streams
+const eventStreamMediaType = "text/event-stream"
+
+/* A Server-Sent Event */
+type Event struct {
+	//Identifier of the last event received so far, sent back as Last-Event-ID when reconnecting
+	ID string
+	//Type of the event, message by default
+	Event string
+	//Payload of the event, its lines joined with \n
+	Data string
+	//Reconnection delay advised by the server along with this event, 0 if none
+	Retry time.Duration
+}
+
+/* Parser of a text/event-stream body */
+type eventReader struct {
+	reader *bufio.Reader
+	//Identifier of the last event, it persists across events
+	lastEventID string
+	//Last reconnection delay advised by the server, 0 if none
+	retry time.Duration
+}
+
+/* Read the next event, the comments and the events without data are skipped.
+Returns io.EOF at the end of the stream, an incomplete last event being discarded */
+func (parser *eventReader) next() (Event, error) {
+	event := Event{}
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := parser.reader.ReadString('\n')
+		if err != nil {
+			return Event{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		//An empty line dispatches the event
+		if len(line) == 0 {
+			if !hasData {
+				event = Event{}
+				continue
+			}
+			event.ID = parser.lastEventID
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			if len(event.Event) == 0 {
+				event.Event = "message"
+			}
+			return event, nil
+		}
+		//Comment
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+			hasData = true
+		case "event":
+			event.Event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				parser.lastEventID = value
+			}
+		case "retry":
+			if milliseconds, err := strconv.ParseUint(value, 10, 63); err == nil {
+				parser.retry = time.Duration(milliseconds) * time.Millisecond
+				event.Retry = parser.retry
+			}
+		}
+	}
+}
+
+/* Subscription to a Server-Sent Events endpoint */
+type EventStream struct {
+	//Events received, closed once the subscription ends
+	Events <-chan Event
+	//Reason why the subscription ended
+	err   error
+	mutex sync.Mutex
+}
+
+/* Reason why the subscription ended, only meaningful once Events is closed.
+Returns the cancellation error of the context, or the answer of the server that prevented to reconnect */
+func (stream *EventStream) Err() error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return stream.err
+}
+
+/*
+Subscribe to the Server-Sent Events sent by this resource, with optional values for named parameters within the URL, see Request().
+The events are delivered on the channel of the returned EventStream, which is unbuffered, until ctx is cancelled.
+The stream is reopened after a delay whenever it ends, with the Last-Event-ID of the last event received. The connection itself is made with the retrier of the resource.
+If the server cannot be reached, the delay doubles on every failed connection, up to maxReconnectionDelay, until the stream is reopened.
+The subscription ends if the server answers with a non-2xx status code as an HTTPError, with a 204 status code, or with another media type than text/event-stream.
+The timeouts of the resource only apply until the response headers are received, see WithReconnectionDelay() for the default delay before reconnecting.
+Returns the subscription
+*/
+func (resource *resource) Subscribe(ctx context.Context, urlParameters interface{}, options ...RequestOption) (*EventStream, error) {
+	settings := newRequestOptions(options)
+	actual := resource.override(settings)
+	request, cancel, err := actual.prepare(ctx, http.MethodGet, urlParameters, nil, settings)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	request.Header.Set("Accept", eventStreamMediaType)
+	request.Header.Set("Cache-Control", "no-cache")
+
+	delay := settings.reconnectionDelay
+	if delay <= 0 {
+		delay = defaultReconnectionDelay
+	}
+
+	events := make(chan Event)
+	stream := &EventStream{Events: events}
+	go func() {
+		defer close(events)
+		defer cancel()
+		err := actual.subscribe(request, events, delay)
+		stream.mutex.Lock()
+		stream.err = err
+		stream.mutex.Unlock()
+	}()
+	return stream, nil
+}
+
+/* Read the event stream, and reconnect whenever it ends.
+Returns the reason why the subscription ended */
+func (resource *resource) subscribe(request *http.Request, events chan<- Event, delay time.Duration) error {
+	ctx := request.Context()
+	parser := &eventReader{}
+	backoff := delay
+	for {
+		connection := request.Clone(ctx)
+		if len(parser.lastEventID) > 0 {
+			connection.Header.Set("Last-Event-ID", parser.lastEventID)
+		}
+		body, err := resource.connect(connection)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) || errors.Is(err, ErrUnsupportedMediaType) {
+				return err
+			}
+			//The server cannot be reached for now, try again later and less and less often
+			if err := wait(ctx, backoff); err != nil {
+				return err
+			}
+			backoff = min(2*backoff, max(delay, maxReconnectionDelay))
+			continue
+		}
+		if body == nil {
+			//The server asked us to stop
+			return nil
+		}
+
+		parser.reader = bufio.NewReader(body)
+		for {
+			event, err := parser.next()
+			if err != nil {
+				break
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				body.Close()
+				return ctx.Err()
+			}
+		}
+		body.Close()
+
+		//Wait before reconnecting, as long as advised by the server if it did
+		if parser.retry > 0 {
+			delay = parser.retry
+		}
+		backoff = delay
+		if err := wait(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+/* Wait for the given delay.
+Returns the cancellation error of the context if it ends first */
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/* Open the event stream, the timeouts of the resource only apply until the response headers are received.
+Returns the body of the stream, nil if the server asked not to reconnect */
+func (resource *resource) connect(request *http.Request) (io.ReadCloser, error) {
+	response, tries, err := resource.open(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNoContent {
+		response.Body.Close()
+		return nil, nil
+	}
+	if !isSuccessful(response.StatusCode) {
+		return nil, resource.readHTTPError(request, response, tries)
+	}
+	if contentType := response.Header.Get("Content-Type"); len(contentType) > 0 {
+		if match, _ := serializers.Match(contentType, []string{eventStreamMediaType}); match == nil {
+			response.Body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+		}
+	}
+	return response.Body, nil
+}
@@ -0,0 +1,222 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, parse an event stream */
+func TestEventReaderNominal(t *testing.T) {
+	input := ": welcome\r\n" +
+		"data: first\r\n" +
+		"data:second line\r\n" +
+		"\r\n" +
+		"event: update\n" +
+		"id: 42\n" +
+		"retry: 1500\n" +
+		"data: {\"id\":42}\n" +
+		"\n" +
+		"retry: 200\n" +
+		"\n" +
+		"data\n" +
+		"unknown: field\n" +
+		"\n" +
+		"data: incomplete"
+	parser := &eventReader{reader: bufio.NewReader(strings.NewReader(input))}
+	expected := []Event{
+		{Event: "message", Data: "first\nsecond line"},
+		{ID: "42", Event: "update", Data: `{"id":42}`, Retry: 1500 * time.Millisecond},
+		{ID: "42", Event: "message", Data: ""},
+	}
+	var observed []Event
+	for {
+		event, err := parser.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("next() unexpected error %v", err)
+		}
+		observed = append(observed, event)
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("next() = %v, want %v", observed, expected)
+	}
+	if parser.retry != 200*time.Millisecond {
+		t.Errorf("next() retry = %v, want %v", parser.retry, 200*time.Millisecond)
+	}
+}
+
+/* Nominal case, the events are delivered, and the stream is reopened from the last event until the server stops it */
+func TestResourceSubscribeNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		if observed := req.Header.Get("Accept"); observed != "text/event-stream" {
+			t.Errorf("Subscribe() Accept = %v", observed)
+		}
+		switch pass {
+		case 1:
+			if observed := req.Header.Get("Last-Event-ID"); len(observed) > 0 {
+				t.Errorf("Subscribe() Last-Event-ID = %v", observed)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": []string{"text/event-stream; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader("id: 1\ndata: a\n\nid: 2\ndata: b\n\n")),
+			}, nil
+		default:
+			if observed := req.Header.Get("Last-Event-ID"); observed != "2" {
+				t.Errorf("Subscribe() Last-Event-ID = %v, want 2", observed)
+			}
+			return &http.Response{
+				StatusCode: 204,
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	stream, err := res.Subscribe(context.Background(), nil, WithReconnectionDelay(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Subscribe() unexpected error %v", err)
+	}
+	var data []string
+	for event := range stream.Events {
+		data = append(data, event.ID+":"+event.Data)
+	}
+	if !reflect.DeepEqual(data, []string{"1:a", "2:b"}) || stream.Err() != nil || pass != 2 {
+		t.Errorf("Subscribe() = %v, %v after %v connections", data, stream.Err(), pass)
+	}
+}
+
+/* Nominal case, the subscription stops with the context, even while waiting for events */
+func TestResourceSubscribeCancelNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.Write([]byte("data: ping\n\n"))
+			<-req.Context().Done()
+			writer.CloseWithError(req.Context().Err())
+		}()
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       reader,
+		}, nil
+	}
+	//The timeout of the resource doesn't interrupt the stream
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ := res.Subscribe(ctx, nil)
+	if event := <-stream.Events; event.Data != "ping" {
+		t.Fatalf("Subscribe() = %v", event)
+	}
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	select {
+	case _, open := <-stream.Events:
+		if open {
+			t.Fatalf("Subscribe() unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Subscribe() was not stopped")
+	}
+	if !errors.Is(stream.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want %v", stream.Err(), context.Canceled)
+	}
+}
+
+/* Nominal case, the subscription survives a server that cannot be reached for a while */
+func TestResourceSubscribeUnreachableNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		if pass < 4 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       io.NopCloser(strings.NewReader("data: back\n\n")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ := res.Subscribe(ctx, nil, WithReconnectionDelay(time.Millisecond))
+	select {
+	case event := <-stream.Events:
+		if event.Data != "back" || pass != 4 {
+			t.Errorf("Subscribe() = %v after %v tries", event, pass)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Subscribe() did not reconnect, Err() = %v", stream.Err())
+	}
+	cancel()
+	for range stream.Events {
+	}
+	if !errors.Is(stream.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want %v", stream.Err(), context.Canceled)
+	}
+}
+
+/* Error case, the server fails, even after the retries */
+func TestResourceSubscribeError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier())
+
+	stream, _ := res.Subscribe(context.Background(), nil)
+	for range stream.Events {
+		t.Errorf("Subscribe() unexpected event")
+	}
+	if !errors.Is(stream.Err(), ErrServerError) || pass != 3 {
+		t.Errorf("Err() = %v after %v tries, want %v", stream.Err(), pass, ErrServerError)
+	}
+}
+
+/* Error case, the server does not send an event stream */
+func TestResourceSubscribeMediaTypeError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	stream, _ := res.Subscribe(context.Background(), nil)
+	for range stream.Events {
+	}
+	if !errors.Is(stream.Err(), ErrUnsupportedMediaType) {
+		t.Errorf("Err() = %v, want %v", stream.Err(), ErrUnsupportedMediaType)
+	}
+}
@@ -0,0 +1,21 @@
+package limiters
+
+import (
+	"errors"
+	"net/http"
+)
+
+// The request cannot be sent without going beyond the rate limit, in time
+var ErrRateLimitExceeded = errors.New("The rate limit is exceeded")
+
+/* Throttle the outgoing requests */
+type Limiter interface {
+	//Wait for the permission to send the request, within its context, or return ErrRateLimitExceeded if it cannot be sent in time
+	Wait(request *http.Request) error
+}
+
+/* Limiter which can adjust itself to the quota advertised by the server */
+type Tuner interface {
+	//Adjust the limiter according to the rate limit headers of the response received for the request, as actually sent
+	Observe(request *http.Request, response *http.Response)
+}
@@ -0,0 +1,46 @@
+package limiters
+
+import (
+	"net/http"
+	"sync"
+)
+
+/* One limiter per host, e.g. to share the quota of a host between all the resources targeting it */
+type perHost struct {
+	//Build the limiter of a new host
+	factory func() Limiter
+	//Limiters per host
+	limiters map[string]Limiter
+	mutex    sync.Mutex
+}
+
+/* perHost c'tor.
+factory is called to build the limiter of every new host */
+func NewPerHost(factory func() Limiter) *perHost {
+	return &perHost{
+		factory:  factory,
+		limiters: map[string]Limiter{},
+	}
+}
+
+/* Get, or build, the limiter of a host */
+func (limiter *perHost) limiterOf(host string) Limiter {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	hostLimiter, ok := limiter.limiters[host]
+	if !ok {
+		hostLimiter = limiter.factory()
+		limiter.limiters[host] = hostLimiter
+	}
+	return hostLimiter
+}
+
+func (limiter *perHost) Wait(request *http.Request) error {
+	return limiter.limiterOf(request.URL.Host).Wait(request)
+}
+
+func (limiter *perHost) Observe(request *http.Request, response *http.Response) {
+	if tuner, ok := limiter.limiterOf(request.URL.Host).(Tuner); ok {
+		tuner.Observe(request, response)
+	}
+}
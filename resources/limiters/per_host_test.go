@@ -0,0 +1,50 @@
+package limiters
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+/* Nominal case, every host has its own quota */
+func TestPerHostNominal(t *testing.T) {
+	built := 0
+	limiter := NewPerHost(func() Limiter {
+		built++
+		return NewTokenBucket(1, 1).WithBlocking(false)
+	})
+
+	first, _ := http.NewRequest("GET", "http://nowhere/a", nil)
+	second, _ := http.NewRequest("GET", "http://elsewhere/a", nil)
+	if err := limiter.Wait(first); err != nil {
+		t.Fatalf("Wait() unexpected error %v", err)
+	}
+	if err := limiter.Wait(second); err != nil {
+		t.Fatalf("Wait() unexpected error %v", err)
+	}
+	third, _ := http.NewRequest("POST", "http://nowhere/b", nil)
+	if err := limiter.Wait(third); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRateLimitExceeded)
+	}
+	if built != 2 {
+		t.Errorf("NewPerHost() built %v limiters, want 2", built)
+	}
+}
+
+/* Nominal case, the responses are observed by the limiter of their host */
+func TestPerHostObserveNominal(t *testing.T) {
+	limiter := NewPerHost(func() Limiter {
+		return NewTokenBucket(10, 10).WithAutoTune(true)
+	})
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	limiter.Observe(req, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Ratelimit-Remaining": []string{"2"}},
+	})
+	observed := limiter.limiterOf("nowhere").(*tokenBucket)
+	other := limiter.limiterOf("elsewhere").(*tokenBucket)
+	if observed.tokens > 2 || other.tokens != 10 {
+		t.Errorf("Observe() left %v and %v tokens", observed.tokens, other.tokens)
+	}
+}
@@ -0,0 +1,156 @@
+package limiters
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+/* Let the requests through at a steady rate, with some bursts allowed.
+A bucket holding at most burst tokens is refilled at rate tokens per second, every request takes a token.
+With a burst of 1 it behaves like a leaky bucket, evenly spacing the requests */
+type tokenBucket struct {
+	//Tokens added per second
+	rate float64
+	//Maximum number of tokens
+	burst float64
+	//Wait for a token rather than rejecting the request right away
+	blocking bool
+	//Adjust to the quota advertised by the server
+	autoTune bool
+	//Time source
+	clock misc.Clock
+	//Available tokens, negative when reserved by waiting requests
+	tokens float64
+	//Last time the bucket was refilled
+	last time.Time
+	//No token is delivered before then, as advised by the server
+	pausedUntil time.Time
+	mutex       sync.Mutex
+}
+
+/* tokenBucket c'tor.
+rate is the number of requests allowed per second, and burst how many can be sent at once. 1 is the minimum burst.
+By default the requests wait for their turn, and the server headers are ignored */
+func NewTokenBucket(rate float64, burst uint) *tokenBucket {
+	if burst == 0 {
+		burst = 1
+	}
+	clock := misc.NewSystemClock()
+	return &tokenBucket{
+		rate:     rate,
+		burst:    float64(burst),
+		blocking: true,
+		clock:    clock,
+		tokens:   float64(burst),
+		last:     clock.Now(),
+	}
+}
+
+/* Do we wait for a token or reject the request right away with ErrRateLimitExceeded.
+Returns the updated limiter */
+func (limiter *tokenBucket) WithBlocking(blocking bool) *tokenBucket {
+	limiter.blocking = blocking
+	return limiter
+}
+
+/* Do we adjust to the RateLimit-*, X-RateLimit-*, and Retry-After headers of the responses.
+Returns the updated limiter */
+func (limiter *tokenBucket) WithAutoTune(autoTune bool) *tokenBucket {
+	limiter.autoTune = autoTune
+	return limiter
+}
+
+/* Use a specific time source.
+Returns the updated limiter */
+func (limiter *tokenBucket) WithClock(clock misc.Clock) *tokenBucket {
+	if clock != nil {
+		limiter.clock = clock
+		limiter.last = clock.Now()
+	}
+	return limiter
+}
+
+/* Add the tokens accumulated since the last refill, the lock must be held */
+func (limiter *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(limiter.last); elapsed > 0 {
+		limiter.tokens += elapsed.Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+		limiter.last = now
+	}
+}
+
+func (limiter *tokenBucket) Wait(request *http.Request) error {
+	ctx := request.Context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	limiter.mutex.Lock()
+	now := limiter.clock.Now()
+	limiter.refill(now)
+	//Reserve a token, possibly not available yet
+	var wait time.Duration
+	if limiter.tokens < 1 {
+		if limiter.rate <= 0 {
+			limiter.mutex.Unlock()
+			return ErrRateLimitExceeded
+		}
+		wait = time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+	}
+	if pause := limiter.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	if wait > 0 {
+		//Don't wait for nothing
+		deadline, hasDeadline := ctx.Deadline()
+		if !limiter.blocking || (hasDeadline && now.Add(wait).After(deadline)) {
+			limiter.mutex.Unlock()
+			return ErrRateLimitExceeded
+		}
+	}
+	limiter.tokens--
+	limiter.mutex.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	if err := limiter.clock.Sleep(ctx, wait); err != nil {
+		//Give the reserved token back
+		limiter.mutex.Lock()
+		limiter.tokens++
+		limiter.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (limiter *tokenBucket) Observe(request *http.Request, response *http.Response) {
+	if !limiter.autoTune || response == nil {
+		return
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := limiter.clock.Now()
+	limiter.refill(now)
+
+	//The server knows better how many requests are left
+	rateLimit, ok := misc.ParseRateLimit(response.Header, now)
+	if ok && rateLimit.Remaining >= 0 && float64(rateLimit.Remaining) < limiter.tokens {
+		limiter.tokens = float64(rateLimit.Remaining)
+	}
+	//Stop sending anything until the quota is reset, or for as long as advised
+	var pause time.Duration
+	if response.StatusCode == http.StatusTooManyRequests || (ok && rateLimit.Remaining == 0) {
+		if advisedDelay, advised := misc.RetryAfter(response.Header, now); advised {
+			pause = advisedDelay
+		}
+	}
+	if until := now.Add(pause); until.After(limiter.pausedUntil) {
+		limiter.pausedUntil = until
+	}
+}
@@ -0,0 +1,149 @@
+package limiters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, the burst goes through right away, then the requests are spaced out */
+func TestTokenBucketNominal(t *testing.T) {
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(2, 3).WithClock(&clock)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(req); err != nil {
+			t.Fatalf("Wait() unexpected error %v", err)
+		}
+	}
+	if expected := []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}; !reflect.DeepEqual(clock.Sleeps, expected) {
+		t.Errorf("Wait() slept %v, want %v", clock.Sleeps, expected)
+	}
+}
+
+/* Nominal case, the bucket is refilled over time without going beyond the burst */
+func TestTokenBucketRefillNominal(t *testing.T) {
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(1, 2).WithClock(&clock).WithBlocking(false)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	limiter.Wait(req)
+	limiter.Wait(req)
+	clock.Current = clock.Current.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(req); err != nil {
+			t.Fatalf("Wait() unexpected error %v", err)
+		}
+	}
+	if err := limiter.Wait(req); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRateLimitExceeded)
+	}
+}
+
+/* Error case, the request is rejected instead of waiting */
+func TestTokenBucketRejectError(t *testing.T) {
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(1, 1).WithClock(&clock).WithBlocking(false)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	if err := limiter.Wait(req); err != nil {
+		t.Fatalf("Wait() unexpected error %v", err)
+	}
+	if err := limiter.Wait(req); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRateLimitExceeded)
+	}
+	if len(clock.Sleeps) != 0 {
+		t.Errorf("Wait() slept %v", clock.Sleeps)
+	}
+}
+
+/* Error case, the request would be sent beyond its deadline */
+func TestTokenBucketDeadlineError(t *testing.T) {
+	clock := mocks.Clock{Current: time.Now()}
+	limiter := NewTokenBucket(0.1, 1).WithClock(&clock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://nowhere", nil)
+	limiter.Wait(req)
+	if err := limiter.Wait(req); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRateLimitExceeded)
+	}
+}
+
+/* Error case, the request is cancelled while waiting, its token is given back */
+func TestTokenBucketCancelError(t *testing.T) {
+	limiter := NewTokenBucket(1, 1)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	limiter.Wait(req)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(req.WithContext(ctx)); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRateLimitExceeded)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := limiter.Wait(req.WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() = %v, want %v", err, context.Canceled)
+	}
+	if limiter.tokens < -0.5 {
+		t.Errorf("Wait() kept the token of a cancelled request, %v tokens left", limiter.tokens)
+	}
+}
+
+/* Nominal case, the limiter waits for the quota advertised by the server to be reset */
+func TestTokenBucketAutoTuneNominal(t *testing.T) {
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(10, 10).WithClock(&clock).WithAutoTune(true)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	limiter.Wait(req)
+	limiter.Observe(req, &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Ratelimit-Limit":     []string{"10"},
+			"Ratelimit-Remaining": []string{"0"},
+			"Ratelimit-Reset":     []string{"5"},
+		},
+	})
+	if err := limiter.Wait(req); err != nil {
+		t.Fatalf("Wait() unexpected error %v", err)
+	}
+	if expected := []time.Duration{5 * time.Second}; !reflect.DeepEqual(clock.Sleeps, expected) {
+		t.Errorf("Wait() slept %v, want %v", clock.Sleeps, expected)
+	}
+
+	//The remaining quota caps the burst
+	limiter.Observe(req, &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"X-Ratelimit-Remaining": []string{"1"}},
+	})
+	if limiter.tokens > 1 {
+		t.Errorf("Observe() left %v tokens, want 1", limiter.tokens)
+	}
+}
+
+/* Nominal case, the server headers are ignored by default */
+func TestTokenBucketNoAutoTuneNominal(t *testing.T) {
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(10, 10).WithClock(&clock)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	limiter.Observe(req, &http.Response{
+		StatusCode: 429,
+		Header:     http.Header{"Retry-After": []string{"5"}},
+	})
+	if err := limiter.Wait(req); err != nil || len(clock.Sleeps) != 0 {
+		t.Errorf("Wait() = %v after sleeping %v", err, clock.Sleeps)
+	}
+}
@@ -0,0 +1,182 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/misc"
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+/* A single attempt at sending a request and receiving its response, as seen by the middlewares.
+Its response body can be decoded with Body() within the maximum response size of the resource, unless it is streamed, see Stream(), Subscribe(), and Dial() */
+type Exchange struct {
+	//Request to send for this attempt, can be replaced or modified before calling the next handler
+	Request *http.Request
+	//Response received for this attempt, only available once the next handler returned without error
+	Response *http.Response
+	//Number of this attempt, starting at 1, as driven by the retrier
+	Attempt uint
+	//Response body (un)marshaller
+	marshaller serializers.Marshaller
+	//Maximum size of the response body read in memory, 0 means no limit
+	maxResponseSize int64
+	//Is the response body read as it comes, or is the connection upgraded, rather than read in memory
+	streamed bool
+	//Is the response body already decoded
+	decoded bool
+	//Decoded response body
+	body interface{}
+	//Decoding error of the response body
+	decodeErr error
+}
+
+/* Send the request of an exchange and fill in its response.
+Only transport errors are expected to be returned, not unsuccessful HTTP status codes */
+type Handler func(exchange *Exchange) error
+
+/* Wrap a handler in order to act before and/or after the next ones in the chain */
+type Middleware func(next Handler) Handler
+
+/* Decode the response body with the resource marshaller.
+The body is read and decoded only once, whoever asks first, and remains readable afterward by the next readers.
+Returns the structured body of the response, nil if none, ErrResponseTooLarge if it is larger than the maximum response size of the resource,
+or ErrStreamedResponse if the response is streamed or upgraded, in which case it is left untouched */
+func (exchange *Exchange) Body() (interface{}, error) {
+	if exchange.decoded {
+		return exchange.body, exchange.decodeErr
+	}
+	if exchange.Response == nil || exchange.Response.Body == nil {
+		return nil, nil
+	}
+	exchange.decoded = true
+	if exchange.streamed || exchange.Response.StatusCode == http.StatusSwitchingProtocols {
+		exchange.decodeErr = ErrStreamedResponse
+		return nil, exchange.decodeErr
+	}
+
+	body := exchange.Response.Body
+	limited, err := limitBody(exchange.Response, exchange.maxResponseSize)
+	if err != nil {
+		exchange.decodeErr = err
+		return nil, err
+	}
+	rawBody, err := ioutil.ReadAll(limited)
+	if err != nil {
+		//Let the next readers read the body as it was, and fail the same way
+		exchange.Response.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(rawBody), body), body}
+		exchange.decodeErr = err
+		return nil, err
+	}
+	body.Close()
+	//Let the next readers read the body again
+	exchange.Response.Body = ioutil.NopCloser(bytes.NewReader(rawBody))
+
+	exchange.body, exchange.decodeErr = decodeResponseBody(bytes.NewReader(rawBody), exchange.Response.Header["Content-Type"], exchange.marshaller)
+	return exchange.body, exchange.decodeErr
+}
+
+/* Add middlewares to this resource.
+They are executed in the given order around every attempt at sending a request, the first one being the outermost.
+Returns the updated resource */
+func (resource *resource) Use(middlewares ...Middleware) *resource {
+	for _, middleware := range middlewares {
+		if middleware != nil {
+			resource.middlewares = append(resource.middlewares, middleware)
+		}
+	}
+	return resource
+}
+
+/* Chain the middlewares of the resource around the actual HTTP client.
+The built-in stages, e.g. the authentication, are the innermost ones, right before the HTTP client.
+Returns the resulting handler */
+func (resource *resource) handler() Handler {
+	client := resource.client
+	handler := Handler(func(exchange *Exchange) error {
+		var err error
+		exchange.Response, err = client.Do(exchange.Request)
+		return err
+	})
+	if resource.limiter != nil {
+		handler = limit(resource.limiter)(handler)
+	}
+	if resource.authenticator != nil {
+		handler = authenticate(resource.authenticator)(handler)
+	}
+	for i := len(resource.middlewares) - 1; i >= 0; i-- {
+		handler = resource.middlewares[i](handler)
+	}
+	return handler
+}
+
+/* HTTP client running every attempt made by a retrier through a handler chain */
+type pipeline struct {
+	//Middlewares chained around the actual HTTP client
+	handler Handler
+	//Response body (un)marshaller
+	marshaller serializers.Marshaller
+	//Timeout of every attempt, 0 means no limit
+	attemptTimeout time.Duration
+	//Maximum size of the response body read in memory, 0 means no limit
+	maxResponseSize int64
+	//Is the response body read as it comes rather than in memory
+	streamed bool
+	//Number of attempts made so far, they may be made in parallel
+	attempts uint64
+}
+
+/* pipeline c'tor, a new pipeline must be used for every call in order to properly count the attempts */
+func newPipeline(handler Handler, marshaller serializers.Marshaller, attemptTimeout time.Duration) *pipeline {
+	return &pipeline{
+		handler:        handler,
+		marshaller:     marshaller,
+		attemptTimeout: attemptTimeout,
+	}
+}
+
+/* Tell how the response body is to be read, in memory within maxResponseSize, 0 meaning no limit, or as it comes if streamed.
+Returns the updated pipeline */
+func (pipeline *pipeline) withBody(maxResponseSize int64, streamed bool) *pipeline {
+	pipeline.maxResponseSize = maxResponseSize
+	pipeline.streamed = streamed
+	return pipeline
+}
+
+func (pipeline *pipeline) Do(request *http.Request) (*http.Response, error) {
+	attempt := uint(atomic.AddUint64(&pipeline.attempts, 1))
+
+	//Every attempt gets its own deadline, which must outlive the reading of the response body
+	cancel := context.CancelFunc(func() {})
+	if pipeline.attemptTimeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(request.Context(), pipeline.attemptTimeout)
+		request = request.WithContext(ctx)
+	}
+
+	exchange := &Exchange{
+		Request:         request,
+		Attempt:         attempt,
+		marshaller:      pipeline.marshaller,
+		maxResponseSize: pipeline.maxResponseSize,
+		streamed:        pipeline.streamed,
+	}
+	err := pipeline.handler(exchange)
+	if err != nil || exchange.Response == nil || exchange.Response.Body == nil {
+		cancel()
+	} else {
+		misc.CancelOnClose(exchange.Response, cancel)
+	}
+	return exchange.Response, err
+}
+
+// Make sure the pipeline can be used by the retriers
+var _ misc.HttpClient = (*pipeline)(nil)
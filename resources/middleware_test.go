@@ -0,0 +1,138 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, middlewares are executed in order around every attempt */
+func TestMiddlewareOrderNominal(t *testing.T) {
+	expectedTrace := []string{"a:1", "b:1", "b:1:503", "a:1:503", "a:2", "b:2", "b:2:200", "a:2:200"}
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	// Send back a retryable status code first, then a 200
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		statusCode := 200
+		if pass == 1 {
+			statusCode = 503
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	var trace []string
+	tracer := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(exchange *Exchange) error {
+				trace = append(trace, name+":"+strconv.Itoa(int(exchange.Attempt)))
+				err := next(exchange)
+				trace = append(trace, name+":"+strconv.Itoa(int(exchange.Attempt))+":"+strconv.Itoa(exchange.Response.StatusCode))
+				return err
+			}
+		}
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).Use(tracer("a"), tracer("b"))
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(trace, expectedTrace) {
+		t.Errorf("Use() = %v, want %v", trace, expectedTrace)
+	}
+}
+
+/* Nominal case, a middleware reads the decoded body without preventing the caller from getting it */
+func TestMiddlewareBodyNominal(t *testing.T) {
+	expectedBody := map[string]interface{}{
+		"token": "zufeb5e1b6e1b6eb",
+	}
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"token":"zufeb5e1b6e1b6eb"}`)),
+		}, nil
+	}
+	var observedMiddlewareBody interface{}
+	res := NewResource(url).WithClient(&mockClient).Use(func(next Handler) Handler {
+		return func(exchange *Exchange) error {
+			err := next(exchange)
+			if err == nil {
+				observedMiddlewareBody, err = exchange.Body()
+			}
+			return err
+		}
+	})
+
+	call, _, _ := res.Request("GET", nil, nil)
+	observedBody, _, err := call()
+	if err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(observedMiddlewareBody, expectedBody) {
+		t.Errorf("Body() = %v, want %v", observedMiddlewareBody, expectedBody)
+	}
+	if !reflect.DeepEqual(observedBody, expectedBody) {
+		t.Errorf("Call() = %v, want %v", observedBody, expectedBody)
+	}
+}
+
+/* Error case, the middlewares cannot decode a response body beyond the maximum response size, nor a streamed one */
+func TestMiddlewareBodyError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"token":"zufeb5e1b6e1b6eb"}`)),
+		}, nil
+	}
+	var observedErr error
+	res := NewResource(url).WithClient(&mockClient).WithMaxResponseSize(10).Use(func(next Handler) Handler {
+		return func(exchange *Exchange) error {
+			err := next(exchange)
+			if err == nil {
+				_, observedErr = exchange.Body()
+			}
+			return err
+		}
+	})
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Call() = %v, want %v", err, ErrResponseTooLarge)
+	}
+	if !errors.Is(observedErr, ErrResponseTooLarge) {
+		t.Errorf("Body() = %v, want %v", observedErr, ErrResponseTooLarge)
+	}
+
+	streamCall, _, _ := res.Stream("GET", nil, nil)
+	stream, _, err := streamCall()
+	if err != nil {
+		t.Fatalf("Stream() unexpected error %v", err)
+	}
+	defer stream.Close()
+	if !errors.Is(observedErr, ErrStreamedResponse) {
+		t.Errorf("Body() = %v, want %v", observedErr, ErrStreamedResponse)
+	}
+	if content, _ := io.ReadAll(stream); string(content) != `{"token":"zufeb5e1b6e1b6eb"}` {
+		t.Errorf("Stream() = %s", content)
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources"
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+/* Timestamp every attempt with a Date header, as some APIs require it, e.g. to sign the requests.
+The time comes from the given clock, the wall clock if none is provided */
+func Date(clock misc.Clock) resources.Middleware {
+	if clock == nil {
+		clock = misc.NewSystemClock()
+	}
+	return func(next resources.Handler) resources.Handler {
+		return func(exchange *resources.Exchange) error {
+			request := exchange.Request.Clone(exchange.Request.Context())
+			request.Header.Set("Date", clock.Now().UTC().Format(http.TimeFormat))
+			exchange.Request = request
+			return next(exchange)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources"
+	"github.com/okayawright/exp_http_client/resources/mocks"
+	"github.com/okayawright/exp_http_client/resources/retriers"
+)
+
+/* Nominal case, every attempt is stamped with the time of the clock */
+func TestDateNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	clock := &mocks.Clock{Current: time.Date(2021, time.October, 4, 12, 30, 0, 0, time.FixedZone("CEST", 2*3600))}
+	var dates []string
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		dates = append(dates, req.Header.Get("Date"))
+		if len(dates) == 1 {
+			return &http.Response{
+				StatusCode: 503,
+				Header:     http.Header{"Retry-After": []string{"2"}},
+				Body:       io.NopCloser(strings.NewReader("{}")),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	retrier := retriers.NewExponentialRetrier().WithClock(clock)
+	res := resources.NewResource(url).WithClient(&mockClient).WithRetrier(retrier).Use(Date(clock))
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	expected := []string{"Mon, 04 Oct 2021 10:30:00 GMT", "Mon, 04 Oct 2021 10:30:02 GMT"}
+	if !reflect.DeepEqual(dates, expected) {
+		t.Errorf("Date() = %v, want %v", dates, expected)
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources"
+)
+
+/* Set a header on every attempt, overriding any existing value */
+func Header(key string, value string) resources.Middleware {
+	return Headers(http.Header{key: []string{value}})
+}
+
+/* Set a set of headers on every attempt, overriding any existing value.
+The request of the exchange is cloned beforehand as it may be shared between concurrent calls */
+func Headers(headers http.Header) resources.Middleware {
+	return func(next resources.Handler) resources.Handler {
+		return func(exchange *resources.Exchange) error {
+			request := exchange.Request.Clone(exchange.Request.Context())
+			for k, v := range headers {
+				request.Header.Del(k)
+				for _, vv := range v {
+					request.Header.Add(k, vv)
+				}
+			}
+			exchange.Request = request
+			return next(exchange)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"testing"
+
+	"github.com/okayawright/exp_http_client/resources"
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, a header is injected into every attempt */
+func TestHeaderNominal(t *testing.T) {
+	expected := "exp_http_client/1.0"
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observed := req.Header.Get("User-Agent"); observed != expected {
+			t.Errorf("Header() = %v, want %v", observed, expected)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := resources.NewResource(url).WithClient(&mockClient).Use(Header("User-Agent", expected))
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+}
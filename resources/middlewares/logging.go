@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources"
+)
+
+/* Log every attempt with its outcome and duration, as structured fields.
+If no logger is provided the default one is used. The credentials of the URL, if any, are redacted */
+func Logging(logger *slog.Logger) resources.Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next resources.Handler) resources.Handler {
+		return func(exchange *resources.Exchange) error {
+			start := time.Now()
+			err := next(exchange)
+			attrs := []slog.Attr{
+				slog.String("method", exchange.Request.Method),
+				slog.String("url", exchange.Request.URL.Redacted()),
+				slog.Uint64("attempt", uint64(exchange.Attempt)),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				logger.LogAttrs(exchange.Request.Context(), slog.LevelWarn, "Request attempt failed", append(attrs, slog.Any("error", err))...)
+			} else {
+				logger.LogAttrs(exchange.Request.Context(), slog.LevelInfo, "Request attempt", append(attrs, slog.Int("status", exchange.Response.StatusCode))...)
+			}
+			return err
+		}
+	}
+}
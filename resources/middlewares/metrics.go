@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources"
+)
+
+/* Observe the outcome of an attempt, e.g. to feed a metrics backend.
+statusCode is 0 if no response was received */
+type MetricsFunc func(method string, host string, attempt uint, statusCode int, duration time.Duration, err error)
+
+/* Report every attempt to the given observer */
+func Metrics(observe MetricsFunc) resources.Middleware {
+	return func(next resources.Handler) resources.Handler {
+		return func(exchange *resources.Exchange) error {
+			start := time.Now()
+			err := next(exchange)
+			statusCode := 0
+			if exchange.Response != nil {
+				statusCode = exchange.Response.StatusCode
+			}
+			observe(exchange.Request.Method, exchange.Request.URL.Host, exchange.Attempt, statusCode, time.Since(start), err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	netUrl "net/url"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources"
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Error case, a transport error is reported without any status code */
+func TestMetricsTransportError(t *testing.T) {
+	expectedErr := errors.New("connection reset")
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	}
+	observations := 0
+	res := resources.NewResource(url).WithClient(&mockClient).Use(Metrics(func(method string, host string, attempt uint, statusCode int, duration time.Duration, err error) {
+		observations++
+		if method != "GET" || host != "localhost:8080" || attempt != 1 || statusCode != 0 || !errors.Is(err, expectedErr) {
+			t.Errorf("Metrics() = %v %v %v %v %v", method, host, attempt, statusCode, err)
+		}
+	}))
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, _, err := call(); !errors.Is(err, expectedErr) {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if observations != 1 {
+		t.Errorf("Metrics() observations = %v, want %v", observations, 1)
+	}
+}
@@ -0,0 +1,40 @@
+package misc
+
+import (
+	"context"
+	"time"
+)
+
+/* Source of time, and way to let it pass, which can be replaced in order not to actually wait */
+type Clock interface {
+	//Current time
+	Now() time.Time
+	//Wait for the given duration unless the context is done beforehand, in which case its error is returned
+	Sleep(ctx context.Context, delay time.Duration) error
+}
+
+/* The actual wall clock */
+type systemClock struct{}
+
+/* systemClock c'tor */
+func NewSystemClock() *systemClock {
+	return &systemClock{}
+}
+
+func (clock *systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (clock *systemClock) Sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,32 @@
+package misc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+/* Nominal case, actually wait */
+func TestSystemClockSleepNominal(t *testing.T) {
+	start := time.Now()
+	if err := NewSystemClock().Sleep(context.Background(), 50*time.Millisecond); err != nil {
+		t.Fatalf("Sleep() unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Sleep() took %v, want at least %v", elapsed, 50*time.Millisecond)
+	}
+}
+
+/* Error case, the wait is interrupted by the context */
+func TestSystemClockSleepCancelledError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := NewSystemClock().Sleep(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Sleep() unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Sleep() took %v, it should have been interrupted", elapsed)
+	}
+}
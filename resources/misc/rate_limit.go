@@ -0,0 +1,73 @@
+package misc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Above this value an X-RateLimit-Reset header is an epoch timestamp rather than a number of seconds
+const epochThreshold = 1000000000
+
+/* Rate limit quota advertised by a server */
+type RateLimit struct {
+	//Maximum number of requests allowed within the window, -1 if unknown
+	Limit int64
+	//Number of requests left within the window, -1 if unknown
+	Remaining int64
+	//Time left until the quota is reset, -1 if unknown
+	Reset time.Duration
+}
+
+/* Read the rate limit quota from the IETF RateLimit-* headers, or from the de facto X-RateLimit-* ones.
+Returns the quota, and whether any was advertised */
+func ParseRateLimit(header http.Header, now time.Time) (RateLimit, bool) {
+	rateLimit := RateLimit{Limit: -1, Remaining: -1, Reset: -1}
+	found := false
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		if v, err := strconv.ParseInt(strings.TrimSpace(header.Get(prefix+"Limit")), 10, 64); err == nil && rateLimit.Limit < 0 {
+			rateLimit.Limit = v
+			found = true
+		}
+		if v, err := strconv.ParseInt(strings.TrimSpace(header.Get(prefix+"Remaining")), 10, 64); err == nil && rateLimit.Remaining < 0 {
+			rateLimit.Remaining = v
+			found = true
+		}
+		if v, err := strconv.ParseInt(strings.TrimSpace(header.Get(prefix+"Reset")), 10, 64); err == nil && rateLimit.Reset < 0 {
+			//The IETF header is always a number of seconds, the de facto one is often an epoch timestamp
+			if v > epochThreshold {
+				rateLimit.Reset = time.Unix(v, 0).Sub(now)
+				if rateLimit.Reset < 0 {
+					rateLimit.Reset = 0
+				}
+			} else if v >= 0 {
+				rateLimit.Reset = time.Duration(v) * time.Second
+			}
+			found = true
+		}
+	}
+	return rateLimit, found
+}
+
+/* Read the delay the server advises to wait for before sending a request again.
+Retry-After, either as delta-seconds or as an HTTP-date, takes precedence, then the reset time of an exhausted, or unknown, rate limit quota.
+Returns the delay, and whether any was advised */
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if retryAfter := strings.TrimSpace(header.Get("Retry-After")); len(retryAfter) > 0 {
+		if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			delay := date.Sub(now)
+			if delay < 0 {
+				delay = 0
+			}
+			return delay, true
+		}
+	}
+	if rateLimit, ok := ParseRateLimit(header, now); ok && rateLimit.Remaining <= 0 && rateLimit.Reset >= 0 {
+		return rateLimit.Reset, true
+	}
+	return 0, false
+}
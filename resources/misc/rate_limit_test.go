@@ -0,0 +1,68 @@
+package misc
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+/* Nominal case, Retry-After as delta-seconds */
+func TestRetryAfterSecondsNominal(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"120"}}
+	observed, ok := RetryAfter(header, time.Now())
+	if !ok || observed != 120*time.Second {
+		t.Errorf("RetryAfter() = %v, want %v", observed, 120*time.Second)
+	}
+}
+
+/* Nominal case, Retry-After as an HTTP-date */
+func TestRetryAfterDateNominal(t *testing.T) {
+	now := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	header := http.Header{"Retry-After": []string{"Wed, 01 Dec 2021 08:00:30 GMT"}}
+	observed, ok := RetryAfter(header, now)
+	if !ok || observed != 30*time.Second {
+		t.Errorf("RetryAfter() = %v, want %v", observed, 30*time.Second)
+	}
+}
+
+/* Nominal case, exhausted IETF rate limit quota */
+func TestRetryAfterRateLimitNominal(t *testing.T) {
+	header := http.Header{"Ratelimit-Remaining": []string{"0"}, "Ratelimit-Reset": []string{"7"}}
+	observed, ok := RetryAfter(header, time.Now())
+	if !ok || observed != 7*time.Second {
+		t.Errorf("RetryAfter() = %v, want %v", observed, 7*time.Second)
+	}
+}
+
+/* Nominal case, exhausted de facto rate limit quota with an epoch reset */
+func TestRetryAfterXRateLimitEpochNominal(t *testing.T) {
+	now := time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)
+	header := http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{"1638345612"}}
+	observed, ok := RetryAfter(header, now)
+	if !ok || observed != 12*time.Second {
+		t.Errorf("RetryAfter() = %v, want %v", observed, 12*time.Second)
+	}
+}
+
+/* Corner case, quota not exhausted yet, or invalid header */
+func TestRetryAfterNone(t *testing.T) {
+	for _, header := range []http.Header{
+		{},
+		{"Retry-After": []string{"soon"}},
+		{"Ratelimit-Remaining": []string{"3"}, "Ratelimit-Reset": []string{"7"}},
+	} {
+		if observed, ok := RetryAfter(header, time.Now()); ok {
+			t.Errorf("RetryAfter(%v) = %v, want none", header, observed)
+		}
+	}
+}
+
+/* Nominal case, parse the whole quota */
+func TestParseRateLimitNominal(t *testing.T) {
+	header := http.Header{"Ratelimit-Limit": []string{"100"}, "Ratelimit-Remaining": []string{"42"}, "Ratelimit-Reset": []string{"30"}}
+	observed, ok := ParseRateLimit(header, time.Now())
+	expected := RateLimit{Limit: 100, Remaining: 42, Reset: 30 * time.Second}
+	if !ok || observed != expected {
+		t.Errorf("ParseRateLimit() = %v, want %v", observed, expected)
+	}
+}
@@ -0,0 +1,36 @@
+package misc
+
+import (
+	"errors"
+	"net/http"
+)
+
+// The body of a request has already been consumed and cannot be rebuilt
+var ErrBodyNotReplayable = errors.New("The request body cannot be sent again")
+
+/* Can the request be sent more than once, i.e. it has no body, or its body can be rebuilt with GetBody */
+func Replayable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+/* Clone a request so that it can be sent once more, independently of the other sends of the same request.
+The body is rebuilt with GetBody if available, otherwise the original one-shot body is handed over for the first send only.
+Returns the request to send, or ErrBodyNotReplayable if its body was already consumed */
+func Replay(request *http.Request, first bool) (*http.Request, error) {
+	clone := request.Clone(request.Context())
+	if request.Body == nil || request.Body == http.NoBody {
+		return clone, nil
+	}
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+		return clone, nil
+	}
+	if !first {
+		return nil, ErrBodyNotReplayable
+	}
+	return clone, nil
+}
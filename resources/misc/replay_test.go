@@ -0,0 +1,47 @@
+package misc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+/* Nominal case, the body is rebuilt for every send */
+func TestReplayNominal(t *testing.T) {
+	expected := `{"token":"zufeb5e1b6e1b6eb"}`
+	req, _ := http.NewRequest("POST", "http://nowhere", bytes.NewBufferString(expected))
+	for i := 0; i < 3; i++ {
+		clone, err := Replay(req, i == 0)
+		if err != nil {
+			t.Fatalf("Replay() unexpected error %v", err)
+		}
+		clone.Header.Set("Date", "now")
+		observed, _ := io.ReadAll(clone.Body)
+		if string(observed) != expected {
+			t.Errorf("Replay() = %v, want %v", string(observed), expected)
+		}
+	}
+	if len(req.Header.Get("Date")) > 0 {
+		t.Errorf("Replay() modified the original request")
+	}
+	if !Replayable(req) {
+		t.Errorf("Replayable() = false, want true")
+	}
+}
+
+/* Error case, a streaming body can only be sent once */
+func TestReplayNotReplayableError(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://nowhere", io.MultiReader(strings.NewReader("stream")))
+	if _, err := Replay(req, true); err != nil {
+		t.Fatalf("Replay() unexpected error %v", err)
+	}
+	if _, err := Replay(req, false); !errors.Is(err, ErrBodyNotReplayable) {
+		t.Errorf("Replay() unexpected error %v", err)
+	}
+	if Replayable(req) {
+		t.Errorf("Replayable() = true, want false")
+	}
+}
@@ -0,0 +1,49 @@
+package misc
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+/* Release the connection held by a response that won't be handed over to anyone, by reading its body till the end then closing it.
+A nil response or body is ignored */
+func Discard(response *http.Response) {
+	if response != nil && response.Body != nil {
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+	}
+}
+
+/* Release the context of a response once its body is closed, as this context must outlive the reading of the body.
+The body of an upgraded connection, e.g. a 101 Switching Protocols response, remains writable.
+A nil response or body is ignored */
+func CancelOnClose(response *http.Response, cancel context.CancelFunc) {
+	if response == nil || response.Body == nil {
+		return
+	}
+	if writer, ok := response.Body.(io.Writer); ok {
+		response.Body = &cancelOnCloseWriter{cancelOnClose: cancelOnClose{ReadCloser: response.Body, cancel: cancel}, Writer: writer}
+	} else {
+		response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	}
+}
+
+/* Response body releasing its context once closed */
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+/* Upgraded connection releasing its context once closed */
+type cancelOnCloseWriter struct {
+	cancelOnClose
+	io.Writer
+}
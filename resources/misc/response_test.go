@@ -0,0 +1,78 @@
+package misc
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (body *trackedBody) Close() error {
+	body.closed = true
+	return nil
+}
+
+/* Nominal case, the body is read till the end and closed */
+func TestDiscardNominal(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader("{}")}
+	Discard(&http.Response{Body: body})
+	if !body.closed {
+		t.Errorf("Discard() did not close the body")
+	}
+	if n, _ := body.Read(make([]byte, 1)); n != 0 {
+		t.Errorf("Discard() did not read the body till the end")
+	}
+}
+
+/* Corner case, nothing to discard */
+func TestDiscardNil(t *testing.T) {
+	Discard(nil)
+	Discard(&http.Response{})
+}
+
+/* Nominal case, the context is released once the body is closed */
+func TestCancelOnCloseNominal(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader("{}")}
+	response := &http.Response{Body: body}
+	cancelled := false
+	CancelOnClose(response, func() { cancelled = true })
+	if cancelled {
+		t.Fatalf("CancelOnClose() released the context too early")
+	}
+	response.Body.Close()
+	if !cancelled || !body.closed {
+		t.Errorf("CancelOnClose() = %v, closed %v", cancelled, body.closed)
+	}
+}
+
+type trackedConnection struct {
+	trackedBody
+	written []byte
+}
+
+func (connection *trackedConnection) Write(p []byte) (int, error) {
+	connection.written = append(connection.written, p...)
+	return len(p), nil
+}
+
+/* Nominal case, the body of an upgraded connection remains writable */
+func TestCancelOnCloseWriterNominal(t *testing.T) {
+	connection := &trackedConnection{trackedBody: trackedBody{Reader: strings.NewReader("")}}
+	response := &http.Response{StatusCode: http.StatusSwitchingProtocols, Body: connection}
+	cancelled := false
+	CancelOnClose(response, func() { cancelled = true })
+	writer, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("CancelOnClose() body is not writable anymore")
+	}
+	writer.Write([]byte("ping"))
+	writer.Close()
+	if string(connection.written) != "ping" || !cancelled || !connection.closed {
+		t.Errorf("CancelOnClose() = %q, %v, closed %v", connection.written, cancelled, connection.closed)
+	}
+}
@@ -0,0 +1,311 @@
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentinel errors, to be used with errors.Is()
+var (
+	//The URI template is malformed
+	ErrInvalidTemplate = errors.New("Invalid URI template")
+	//A required variable of the URI template has no value
+	ErrUnresolvedVariable = errors.New("Unresolved URI template variable")
+)
+
+/* Expansion rules of an expression operator, RFC 6570 appendix A */
+type operator struct {
+	//Prefix of the expansion, if any variable is defined
+	first string
+	//Separator between the values
+	separator string
+	//Are the values prefixed by the name of their variable
+	named bool
+	//Suffix of the name of a variable whose value is empty
+	ifEmpty string
+	//Are the reserved characters left as is
+	reserved bool
+	//Can the variables be undefined
+	optional bool
+}
+
+var operators = map[byte]operator{
+	0:   {first: "", separator: ",", named: false, ifEmpty: "", reserved: false},
+	'+': {first: "", separator: ",", named: false, ifEmpty: "", reserved: true},
+	'#': {first: "#", separator: ",", named: false, ifEmpty: "", reserved: true},
+	'.': {first: ".", separator: ".", named: false, ifEmpty: "", reserved: false},
+	'/': {first: "/", separator: "/", named: false, ifEmpty: "", reserved: false},
+	';': {first: ";", separator: ";", named: true, ifEmpty: "", reserved: false},
+	'?': {first: "?", separator: "&", named: true, ifEmpty: "=", reserved: false, optional: true},
+	'&': {first: "&", separator: "&", named: true, ifEmpty: "=", reserved: false, optional: true},
+}
+
+/* A variable of an expression, with its modifier */
+type varSpec struct {
+	name string
+	//Maximum number of characters of the value, 0 means no limit
+	prefix  int
+	explode bool
+}
+
+/* Expand an RFC 6570 URI template, up to level 4, with the given values.
+A value is either a string, a []string list, a map[string]string associative array whose keys are expanded in sorted order, or any other scalar formatted with fmt.Sprint.
+nil, an empty list, and an empty associative array are undefined values.
+The variables of the query expressions, {?var} and {&var}, are optional, whereas all the others are required.
+Returns the expanded URI, ErrInvalidTemplate if the template is malformed, ErrUnresolvedVariable if a required variable is undefined */
+func Expand(template string, values map[string]interface{}) (string, error) {
+	expanded, _, err := expand(template, values)
+	return expanded, err
+}
+
+/* Expand an URI template, see Expand().
+Returns the expanded URI, and the names of the variables found in the template */
+func expand(template string, values map[string]interface{}) (string, map[string]bool, error) {
+	var result strings.Builder
+	referenced := map[string]bool{}
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			encodeLiteral(&result, template)
+			break
+		}
+		encodeLiteral(&result, template[:start])
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("%w: unclosed expression %s", ErrInvalidTemplate, template[start:])
+		}
+		expression := template[start+1 : start+end]
+		template = template[start+end+1:]
+
+		op, specs, err := parseExpression(expression)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, spec := range specs {
+			referenced[spec.name] = true
+		}
+		if err := expandExpression(&result, op, specs, values); err != nil {
+			return "", nil, err
+		}
+	}
+	return result.String(), referenced, nil
+}
+
+/* Parse the content of an expression, between the curly braces.
+Returns the operator and the variables of the expression */
+func parseExpression(expression string) (operator, []varSpec, error) {
+	var code byte
+	if len(expression) > 0 && strings.IndexByte("+#./;?&", expression[0]) >= 0 {
+		code = expression[0]
+		expression = expression[1:]
+	}
+	var specs []varSpec
+	for _, raw := range strings.Split(expression, ",") {
+		spec := varSpec{name: raw}
+		if strings.HasSuffix(raw, "*") {
+			spec.name = strings.TrimSuffix(raw, "*")
+			spec.explode = true
+		} else if name, prefix, found := strings.Cut(raw, ":"); found {
+			length, err := strconv.Atoi(prefix)
+			if err != nil || length < 1 || length > 9999 || strings.HasPrefix(prefix, "0") {
+				return operator{}, nil, fmt.Errorf("%w: invalid prefix %s", ErrInvalidTemplate, raw)
+			}
+			spec.name = name
+			spec.prefix = length
+		}
+		if !isVarName(spec.name) {
+			return operator{}, nil, fmt.Errorf("%w: invalid variable %s", ErrInvalidTemplate, raw)
+		}
+		specs = append(specs, spec)
+	}
+	return operators[code], specs, nil
+}
+
+/* Is it a valid variable name, made of letters, digits, underscores, percent-encoded triplets, and inner dots */
+func isVarName(name string) bool {
+	if len(name) == 0 || name[0] == '.' || name[len(name)-1] == '.' || strings.Contains(name, "..") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		character := name[i]
+		switch {
+		case character == '%':
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return false
+			}
+			i += 2
+		case character == '_' || character == '.' || isAlphaNum(character):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+/* Expand the variables of a single expression */
+func expandExpression(result *strings.Builder, op operator, specs []varSpec, values map[string]interface{}) error {
+	first := true
+	for _, spec := range specs {
+		value := normalize(values[spec.name])
+		if value == nil {
+			if op.optional {
+				continue
+			}
+			return fmt.Errorf("%w: %s", ErrUnresolvedVariable, spec.name)
+		}
+		if first {
+			result.WriteString(op.first)
+			first = false
+		} else {
+			result.WriteString(op.separator)
+		}
+
+		switch typed := value.(type) {
+		case string:
+			if spec.prefix > 0 && utf8.RuneCountInString(typed) > spec.prefix {
+				typed = string([]rune(typed)[:spec.prefix])
+			}
+			writeNamed(result, op, spec.name, typed)
+		case []string:
+			if spec.explode {
+				for i, item := range typed {
+					if i > 0 {
+						result.WriteString(op.separator)
+					}
+					if op.named {
+						writeNamed(result, op, spec.name, item)
+					} else {
+						encodeValue(result, item, op.reserved)
+					}
+				}
+			} else {
+				items := make([]string, len(typed))
+				for i, item := range typed {
+					items[i] = encode(item, op.reserved)
+				}
+				writeComposite(result, op, spec.name, strings.Join(items, ","))
+			}
+		case map[string]string:
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if spec.explode {
+				for i, key := range keys {
+					if i > 0 {
+						result.WriteString(op.separator)
+					}
+					if op.named {
+						writeNamed(result, op, encode(key, op.reserved), typed[key])
+					} else {
+						encodeValue(result, key, op.reserved)
+						result.WriteByte('=')
+						encodeValue(result, typed[key], op.reserved)
+					}
+				}
+			} else {
+				pairs := make([]string, 0, 2*len(keys))
+				for _, key := range keys {
+					pairs = append(pairs, encode(key, op.reserved), encode(typed[key], op.reserved))
+				}
+				writeComposite(result, op, spec.name, strings.Join(pairs, ","))
+			}
+		}
+	}
+	return nil
+}
+
+/* Convert a value into a string, a []string, or a map[string]string.
+Returns nil if the value is undefined */
+func normalize(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return typed
+	case []string:
+		if len(typed) == 0 {
+			return nil
+		}
+		return typed
+	case map[string]string:
+		if len(typed) == 0 {
+			return nil
+		}
+		return typed
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+/* Write a single value, prefixed by its name if the operator requires it */
+func writeNamed(result *strings.Builder, op operator, name string, value string) {
+	if op.named {
+		result.WriteString(name)
+		if len(value) == 0 {
+			result.WriteString(op.ifEmpty)
+			return
+		}
+		result.WriteByte('=')
+	}
+	encodeValue(result, value, op.reserved)
+}
+
+/* Write an already encoded list or associative array, prefixed by its name if the operator requires it */
+func writeComposite(result *strings.Builder, op operator, name string, encoded string) {
+	if op.named {
+		result.WriteString(name)
+		result.WriteByte('=')
+	}
+	result.WriteString(encoded)
+}
+
+func encode(value string, reserved bool) string {
+	var result strings.Builder
+	encodeValue(&result, value, reserved)
+	return result.String()
+}
+
+/* Percent-encode a value, the unreserved characters, and the reserved ones if allowed, are left as is along with the existing percent-encoded triplets */
+func encodeValue(result *strings.Builder, value string, reserved bool) {
+	for i := 0; i < len(value); i++ {
+		character := value[i]
+		switch {
+		case isUnreserved(character):
+			result.WriteByte(character)
+		case reserved && isReserved(character):
+			result.WriteByte(character)
+		case reserved && character == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			result.WriteString(value[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(result, "%%%02X", character)
+		}
+	}
+}
+
+/* Copy the literal part of a template, percent-encoding the characters not allowed in an URI */
+func encodeLiteral(result *strings.Builder, literal string) {
+	encodeValue(result, literal, true)
+}
+
+func isAlphaNum(character byte) bool {
+	return 'a' <= character && character <= 'z' || 'A' <= character && character <= 'Z' || '0' <= character && character <= '9'
+}
+
+func isHex(character byte) bool {
+	return '0' <= character && character <= '9' || 'a' <= character && character <= 'f' || 'A' <= character && character <= 'F'
+}
+
+func isUnreserved(character byte) bool {
+	return isAlphaNum(character) || strings.IndexByte("-._~", character) >= 0
+}
+
+func isReserved(character byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", character) >= 0
+}
@@ -0,0 +1,126 @@
+package misc
+
+import (
+	"errors"
+	"testing"
+)
+
+/* Nominal case, the examples of RFC 6570, the keys of the associative arrays being sorted */
+func TestExpandNominal(t *testing.T) {
+	values := map[string]interface{}{
+		"count":      []string{"one", "two", "three"},
+		"dom":        []string{"example", "com"},
+		"dub":        "me/too",
+		"hello":      "Hello World!",
+		"half":       "50%",
+		"var":        "value",
+		"who":        "fred",
+		"base":       "http://example.com/home/",
+		"path":       "/foo/bar",
+		"list":       []string{"red", "green", "blue"},
+		"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":          6,
+		"x":          1024,
+		"y":          "768",
+		"empty":      "",
+		"empty_keys": map[string]string{},
+		"undef":      nil,
+	}
+	tests := map[string]string{
+		//Level 1
+		"{var}":            "value",
+		"{hello}":          "Hello%20World%21",
+		"{half}":           "50%25",
+		"O{empty}X":        "OX",
+		"{base}index":      "http%3A%2F%2Fexample.com%2Fhome%2Findex",
+		"here?ref={+path}": "here?ref=/foo/bar",
+		//Level 2
+		"{+var}":         "value",
+		"{+hello}":       "Hello%20World!",
+		"{+half}":        "50%25",
+		"{+base}index":   "http://example.com/home/index",
+		"{+path}/here":   "/foo/bar/here",
+		"{#var}":         "#value",
+		"{#hello}":       "#Hello%20World!",
+		"X{#half}":       "X#50%25",
+		"{dub}":          "me%2Ftoo",
+		"{+path,x}/here": "/foo/bar,1024/here",
+		//Level 3
+		"map?{x,y}":           "map?1024,768",
+		"{x,hello,y}":         "1024,Hello%20World%21,768",
+		"{#x,hello,y}":        "#1024,Hello%20World!,768",
+		"X{.var}":             "X.value",
+		"X{.x,y}":             "X.1024.768",
+		"X{.empty}":           "X.",
+		"{/var,x}/here":       "/value/1024/here",
+		"{;x,y}":              ";x=1024;y=768",
+		"{;x,y,empty}":        ";x=1024;y=768;empty",
+		"{?x,y}":              "?x=1024&y=768",
+		"{?x,y,empty}":        "?x=1024&y=768&empty=",
+		"{?x,y,undef}":        "?x=1024&y=768",
+		"?fixed=yes{&x}":      "?fixed=yes&x=1024",
+		"{?undef,empty_keys}": "",
+		//Level 4
+		"{var:3}":             "val",
+		"{var:30}":            "value",
+		"{list}":              "red,green,blue",
+		"{list*}":             "red,green,blue",
+		"{keys}":              "comma,%2C,dot,.,semi,%3B",
+		"{keys*}":             "comma=%2C,dot=.,semi=%3B",
+		"{+path:6}/here":      "/foo/b/here",
+		"{+list}":             "red,green,blue",
+		"{+keys}":             "comma,,,dot,.,semi,;",
+		"{+keys*}":            "comma=,,dot=.,semi=;",
+		"{#path:6}/here":      "#/foo/b/here",
+		"{#list*}":            "#red,green,blue",
+		"{#keys*}":            "#comma=,,dot=.,semi=;",
+		"X{.var:3}":           "X.val",
+		"X{.list}":            "X.red,green,blue",
+		"X{.list*}":           "X.red.green.blue",
+		"X{.keys*}":           "X.comma=%2C.dot=..semi=%3B",
+		"{/var:1,var}":        "/v/value",
+		"{/list*}":            "/red/green/blue",
+		"{/list*,path:4}":     "/red/green/blue/%2Ffoo",
+		"{/keys*}":            "/comma=%2C/dot=./semi=%3B",
+		"{;hello:5}":          ";hello=Hello",
+		"{;list}":             ";list=red,green,blue",
+		"{;list*}":            ";list=red;list=green;list=blue",
+		"{;keys*}":            ";comma=%2C;dot=.;semi=%3B",
+		"{?var:3}":            "?var=val",
+		"{?list}":             "?list=red,green,blue",
+		"{?list*}":            "?list=red&list=green&list=blue",
+		"{?keys}":             "?keys=comma,%2C,dot,.,semi,%3B",
+		"{?keys*}":            "?comma=%2C&dot=.&semi=%3B",
+		"{&var:3}":            "&var=val",
+		"{&list*}":            "&list=red&list=green&list=blue",
+		"{&keys*}":            "&comma=%2C&dot=.&semi=%3B",
+		"find{?year*}":        "find",
+		"{/who,dom}{?count*}": "/fred/example,com?count=one&count=two&count=three",
+	}
+	for template, expected := range tests {
+		observed, err := Expand(template, values)
+		if err != nil || observed != expected {
+			t.Errorf("Expand(%v) = %v, %v, want %v", template, observed, err, expected)
+		}
+	}
+}
+
+/* Error case, malformed templates and missing values */
+func TestExpandError(t *testing.T) {
+	tests := map[string]error{
+		"{var":         ErrInvalidTemplate,
+		"{}":           ErrInvalidTemplate,
+		"{=var}":       ErrInvalidTemplate,
+		"{var:0}":      ErrInvalidTemplate,
+		"{var:10000}":  ErrInvalidTemplate,
+		"{v r}":        ErrInvalidTemplate,
+		"{undef}":      ErrUnresolvedVariable,
+		"{/list*}":     ErrUnresolvedVariable,
+		"{#var,undef}": ErrUnresolvedVariable,
+	}
+	for template, expected := range tests {
+		if observed, err := Expand(template, map[string]interface{}{"var": "value", "list": []string{}}); !errors.Is(err, expected) {
+			t.Errorf("Expand(%v) = %v, %v, want %v", template, observed, err, expected)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package misc
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	netUrl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The URL parameters, or one of their fields, are of an unsupported type
+var ErrUnsupportedParameters = errors.New("Unsupported URL parameters")
+
+/* How a slice is written as a query parameter, set with the options of a query tag */
+type ArrayFormat string
+
+const (
+	//Repeated parameter, ids=1&ids=2, the default
+	RepeatArray ArrayFormat = "repeat"
+	//Comma-separated values, ids=1,2
+	CommaArray ArrayFormat = "comma"
+	//Repeated parameter with brackets, ids[]=1&ids[]=2
+	BracketsArray ArrayFormat = "brackets"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+/* Split URL parameters into the variables of the URL template, and the query parameters to append.
+values is either a map[string]string, or a struct whose exported fields are tagged with:
+- url:"name,omitempty" for a variable of the template,
+- query:"name,omitempty,format" for a query parameter, format being one of the ArrayFormat for a slice.
+A pointer to any of them is accepted as well.
+The fields can be strings, booleans, numbers, encoding.TextMarshaler like time.Time, pointers to them, or slices of them.
+A nil pointer is left undefined, and so is a zero value if omitempty is set.
+Returns the template variables, and the query parameters */
+func parameters(values interface{}) (map[string]interface{}, netUrl.Values, error) {
+	variables := map[string]interface{}{}
+	query := netUrl.Values{}
+	switch typedValues := values.(type) {
+	case nil:
+		return variables, query, nil
+	case map[string]string:
+		for k, v := range typedValues {
+			variables[k] = v
+		}
+		return variables, query, nil
+	case *map[string]string:
+		if typedValues != nil {
+			for k, v := range *typedValues {
+				variables[k] = v
+			}
+		}
+		return variables, query, nil
+	}
+
+	value := reflect.ValueOf(values)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return variables, query, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%w: %T", ErrUnsupportedParameters, values)
+	}
+	if err := structParameters(value, variables, query); err != nil {
+		return nil, nil, err
+	}
+	return variables, query, nil
+}
+
+/* Collect the tagged fields of a struct, embedded structs included */
+func structParameters(value reflect.Value, variables map[string]interface{}, query netUrl.Values) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		urlTag, isVariable := field.Tag.Lookup("url")
+		queryTag, isQuery := field.Tag.Lookup("query")
+		if field.Anonymous && !isVariable && !isQuery {
+			embedded := reflect.Indirect(value.Field(i))
+			if embedded.Kind() == reflect.Struct {
+				if err := structParameters(embedded, variables, query); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if len(field.PkgPath) > 0 || (!isVariable && !isQuery) || urlTag == "-" || queryTag == "-" {
+			continue
+		}
+
+		tag := queryTag
+		if isVariable {
+			tag = urlTag
+		}
+		name, rawOptions, _ := strings.Cut(tag, ",")
+		if len(name) == 0 {
+			name = field.Name
+		}
+		options := strings.Split(rawOptions, ",")
+		if value.Field(i).IsZero() && hasOption(options, "omitempty") {
+			continue
+		}
+		formatted, err := formatParameter(value.Field(i))
+		if err != nil {
+			return fmt.Errorf("%w: field %s", err, field.Name)
+		}
+		if formatted == nil {
+			continue
+		}
+
+		if isVariable {
+			variables[name] = formatted
+			continue
+		}
+		switch typed := formatted.(type) {
+		case string:
+			query.Add(name, typed)
+		case []string:
+			switch {
+			case hasOption(options, string(CommaArray)):
+				query.Add(name, strings.Join(typed, ","))
+			case hasOption(options, string(BracketsArray)):
+				query[name+"[]"] = append(query[name+"[]"], typed...)
+			default:
+				query[name] = append(query[name], typed...)
+			}
+		case map[string]string:
+			return fmt.Errorf("%w: field %s cannot be a query parameter", ErrUnsupportedParameters, field.Name)
+		}
+	}
+	return nil
+}
+
+func hasOption(options []string, option string) bool {
+	for _, candidate := range options {
+		if candidate == option {
+			return true
+		}
+	}
+	return false
+}
+
+/* Format a field into a string, a []string, or a map[string]string, as expected by Expand().
+Returns nil if the field is undefined */
+func formatParameter(value reflect.Value) (interface{}, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if _, ok := textMarshaler(value); ok {
+		return formatScalar(value)
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		//A byte slice is a single value
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return formatScalar(value)
+		}
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, err := formatParameter(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			switch typed := item.(type) {
+			case nil:
+			case string:
+				items = append(items, typed)
+			default:
+				return nil, fmt.Errorf("%w: nested %s", ErrUnsupportedParameters, value.Type())
+			}
+		}
+		return items, nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedParameters, value.Type())
+		}
+		pairs := map[string]string{}
+		for _, key := range value.MapKeys() {
+			item, err := formatParameter(value.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: nested %s", ErrUnsupportedParameters, value.Type())
+			}
+			pairs[key.String()] = text
+		}
+		return pairs, nil
+	}
+	return formatScalar(value)
+}
+
+/* The value as an encoding.TextMarshaler, be it implemented by the value or by its pointer */
+func textMarshaler(value reflect.Value) (encoding.TextMarshaler, bool) {
+	if value.Type().Implements(textMarshalerType) {
+		return value.Interface().(encoding.TextMarshaler), true
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textMarshalerType) {
+		return value.Addr().Interface().(encoding.TextMarshaler), true
+	}
+	return nil, false
+}
+
+/* Format a single value into a string */
+func formatScalar(value reflect.Value) (interface{}, error) {
+	if marshaler, ok := textMarshaler(value); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(bytes), value)
+			return string(bytes), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedParameters, value.Type())
+}
@@ -0,0 +1,105 @@
+package misc
+
+import (
+	"errors"
+	"fmt"
+	netUrl "net/url"
+	"testing"
+	"time"
+)
+
+type version struct {
+	major int
+	minor int
+}
+
+func (v *version) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%d", v.major, v.minor)), nil
+}
+
+type paging struct {
+	Page  int `query:"page,omitempty"`
+	Limit int `query:"limit"`
+}
+
+type search struct {
+	paging
+	User     string     `url:"user"`
+	Segments []string   `url:"segments"`
+	Version  version    `url:"version"`
+	Since    time.Time  `query:"since,omitempty"`
+	Active   *bool      `query:"active"`
+	Tags     []string   `query:"tags,omitempty"`
+	IDs      []int      `query:"ids,comma"`
+	Scores   []float64  `query:"scores,brackets"`
+	Before   *time.Time `query:"before,omitempty"`
+	Ignored  string
+	Skipped  string `url:"-"`
+	private  string `url:"private"`
+}
+
+/* Nominal case, a struct is expanded into the template variables and the query parameters */
+func TestResolveStructNominal(t *testing.T) {
+	u, _ := netUrl.Parse("http://localhost:8080/api/{user}/search{/segments*}?v={version}")
+	active := true
+	observed, err := Resolve(u, &search{
+		paging:   paging{Limit: 10},
+		User:     "julien",
+		Segments: []string{"a b", "c"},
+		Version:  version{major: 1, minor: 2},
+		Since:    time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC),
+		Active:   &active,
+		IDs:      []int{1, 2, 3},
+		Scores:   []float64{0.5, 2},
+		Ignored:  "ignored",
+		Skipped:  "skipped",
+		private:  "private",
+	})
+	expected := "http://localhost:8080/api/julien/search/a%20b/c?active=true&ids=1%2C2%2C3&limit=10&scores%5B%5D=0.5&scores%5B%5D=2&since=2022-05-01T10%3A00%3A00Z&v=1.2"
+	if err != nil || observed.String() != expected {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, expected)
+	}
+}
+
+/* Nominal case, the slices are repeated query parameters by default, and the nil pointers are left out */
+func TestResolveStructRepeatNominal(t *testing.T) {
+	u, _ := netUrl.Parse("http://localhost:8080/api/search")
+	observed, err := Resolve(u, struct {
+		Tags   []string `query:"tag"`
+		Active *bool    `query:"active"`
+		Page   int      `url:"page"`
+	}{Tags: []string{"go", "http"}, Page: 2})
+	expected := "http://localhost:8080/api/search?page=2&tag=go&tag=http"
+	if err != nil || observed.String() != expected {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, expected)
+	}
+}
+
+/* Error case, unsupported parameters or fields, and missing variables */
+func TestResolveStructError(t *testing.T) {
+	u, _ := netUrl.Parse("http://localhost:8080/api/{user}")
+	tests := []struct {
+		name     string
+		values   interface{}
+		expected error
+	}{
+		{"not a struct", 42, ErrUnsupportedParameters},
+		{"unsupported field", struct {
+			User chan int `url:"user"`
+		}{}, ErrUnsupportedParameters},
+		{"map query", struct {
+			User   string            `url:"user"`
+			Labels map[string]string `query:"labels"`
+		}{User: "julien", Labels: map[string]string{"a": "b"}}, ErrUnsupportedParameters},
+		{"omitted variable", struct {
+			User string `url:"user,omitempty"`
+		}{}, ErrUnresolvedVariable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if observed, err := Resolve(u, test.values); !errors.Is(err, test.expected) {
+				t.Errorf("Resolve() = %v, %v, want %v", observed, err, test.expected)
+			}
+		})
+	}
+}
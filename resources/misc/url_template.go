@@ -5,44 +5,82 @@ import (
 	"strings"
 )
 
-/* Try to match and replace the given named parameters, which are enclosed in curly brackets, within the path, fragment and query of the specified URL.
-If none matched we append those parameters as querystrings.
-Any remaining unresolved named parameter are not removed in the url and kept as-is.
-TODO: handle arrays in querystrings, not just as single-value items
-The URL is not modified by side-effect, read the returned URL the see the actual chnages */
-func Resolve(url *netUrl.URL, values *map[string]string) *netUrl.URL {
+/* Expand the given named parameters within the path, query and fragment of the specified URL, which is an RFC 6570 URI template, see Expand().
+The values are percent-encoded according to the expressions they are part of, e.g. {user}, {+path}, {?query,page}, or {/segments*}.
+values is either a map[string]string, or a struct with url and query tags, or a pointer to them, see parameters().
+If a parameter is not part of the template, or is a query parameter, we append it as a querystring.
+The URL is not modified by side-effect, read the returned URL to see the actual changes.
+Returns the resolved URL, ErrUnresolvedVariable if a required variable of the template has no value */
+func Resolve(url *netUrl.URL, values interface{}) (*netUrl.URL, error) {
 	//Shortcut
 	if url == nil {
-		return nil
+		return nil, nil
 	}
 
-	//Shallow cloning of the url in order not to modify the original one
-	copiedUrl := *url
+	variables, query, err := parameters(values)
+	if err != nil {
+		return nil, err
+	}
 
-	//Shortcut
-	if values == nil || len(*values) == 0 {
-		return &copiedUrl
+	//Rebuild the template from the parsed URL, the curly braces being kept as is
+	origin := *url
+	origin.Path, origin.RawPath, origin.RawQuery, origin.Fragment, origin.RawFragment, origin.ForceQuery = "", "", "", "", "", false
+	template := origin.String() + rawPath(url)
+	if len(url.RawQuery) > 0 || url.ForceQuery {
+		template += "?" + url.RawQuery
+	}
+	if len(url.Fragment) > 0 {
+		template += "#" + rawFragment(url)
 	}
 
-	for k, v := range *values {
-		tag := "{" + k + "}"
+	expanded, referenced, err := expand(template, variables)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := netUrl.Parse(expanded)
+	if err != nil {
+		return nil, err
+	}
 
-		//Try to find and replace all occurences of the named parameter within the path, then within the fragment, and finally within the query string
-		hasMatched := false
-		for _, vv := range [3]*string{&copiedUrl.Path, &copiedUrl.Fragment, &copiedUrl.RawQuery} {
-			if strings.Contains(*vv, tag) {
-				*vv = strings.ReplaceAll(*vv, tag, v)
-				hasMatched = true
+	//The parameters missing from the template are added as new query strings
+	for k, v := range variables {
+		if referenced[k] {
+			continue
+		}
+		switch typed := v.(type) {
+		case string:
+			query.Add(k, typed)
+		case []string:
+			query[k] = append(query[k], typed...)
+		case map[string]string:
+			for key, value := range typed {
+				query.Add(key, value)
 			}
 		}
-
-		//If we couldn't find the named parameter it means we need to add it as a new query string
-		if !hasMatched {
-			q := copiedUrl.Query()
-			q.Add(k, v)
-			copiedUrl.RawQuery = q.Encode()
+	}
+	if len(query) > 0 {
+		q := resolved.Query()
+		for k, v := range query {
+			q[k] = append(q[k], v...)
 		}
+		resolved.RawQuery = q.Encode()
 	}
 
-	return &copiedUrl
+	return resolved, nil
+}
+
+/* Path of the URL as it was written, curly braces included */
+func rawPath(url *netUrl.URL) string {
+	if len(url.RawPath) > 0 {
+		return url.RawPath
+	}
+	return strings.NewReplacer("%7B", "{", "%7D", "}").Replace(url.EscapedPath())
+}
+
+/* Fragment of the URL as it was written, curly braces included */
+func rawFragment(url *netUrl.URL) string {
+	if len(url.RawFragment) > 0 {
+		return url.RawFragment
+	}
+	return strings.NewReplacer("%7B", "{", "%7D", "}").Replace(url.EscapedFragment())
 }
@@ -1,6 +1,7 @@
 package misc
 
 import (
+	"errors"
 	netUrl "net/url"
 	"reflect"
 	"testing"
@@ -10,23 +11,22 @@ import (
 func TestReplaceNominal(t *testing.T) {
 	expected, _ := netUrl.Parse("http://localhost:8080/api/julien/info?withBio=true&withCredentials=true&withPhoto=false")
 	u, _ := netUrl.Parse("http://localhost:8080/api/{user}/info?withCredentials={credentials}&withPhoto=false")
-	observed := Resolve(u, &map[string]string{
+	observed, err := Resolve(u, &map[string]string{
 		"user":        "julien",
 		"credentials": "true",
 		"withBio":     "true",
 	})
-	if !reflect.DeepEqual(observed.String(), expected.String()) {
-		t.Errorf("Resolve() = %v, want %v", observed.String(), expected.String())
+	if err != nil || !reflect.DeepEqual(observed.String(), expected.String()) {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, expected.String())
 	}
 }
 
-/* Nominal case, url without any specified named parameter */
+/* Error case, url with a named parameter that is not specified */
 func TestReplaceNoReplacement(t *testing.T) {
-	expected, _ := netUrl.Parse("http://localhost:8080/api/julien/info?withBio={withBio}&withCredentials=true&withPhoto=false")
 	u, _ := netUrl.Parse("http://localhost:8080/api/julien/info?withBio={withBio}&withCredentials=true&withPhoto=false")
-	observed := Resolve(u, nil)
-	if !reflect.DeepEqual(observed.String(), expected.String()) {
-		t.Errorf("Resolve() = %v, want %v", observed.String(), expected.String())
+	observed, err := Resolve(u, nil)
+	if !errors.Is(err, ErrUnresolvedVariable) {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, ErrUnresolvedVariable)
 	}
 }
 
@@ -34,10 +34,28 @@ func TestReplaceNoReplacement(t *testing.T) {
 func TestReplaceProperEscaping(t *testing.T) {
 	expected, _ := netUrl.Parse("http://localhost:8080/api/L%27orec/info?login=OAuth2")
 	u, _ := netUrl.Parse("http://localhost:8080/api/{user}/info?login=OAuth2")
-	observed := Resolve(u, &map[string]string{
+	observed, err := Resolve(u, &map[string]string{
 		"user": "L'orec",
 	})
-	if !reflect.DeepEqual(observed.String(), expected.String()) {
-		t.Errorf("Resolve() = %v, want %v", observed.String(), expected.String())
+	if err != nil || !reflect.DeepEqual(observed.String(), expected.String()) {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, expected.String())
+	}
+}
+
+/* Corner case, the reserved characters of a value cannot alter the structure of the url */
+func TestReplaceReservedCharacters(t *testing.T) {
+	u, _ := netUrl.Parse("http://localhost:8080/api/{user}/files{/path*}{?query,page}#{+section}")
+	observed, err := Resolve(u, &map[string]string{
+		"user":    "julien/../admin?x=1",
+		"path":    "a b",
+		"query":   "50% & more",
+		"section": "part/1",
+	})
+	expected := "http://localhost:8080/api/julien%2F..%2Fadmin%3Fx%3D1/files/a%20b?query=50%25%20%26%20more#part/1"
+	if err != nil || observed.String() != expected {
+		t.Errorf("Resolve() = %v, %v, want %v", observed, err, expected)
+	}
+	if observed.Path != "/api/julien/../admin?x=1/files/a b" || observed.Query().Get("query") != "50% & more" || observed.Query().Has("page") {
+		t.Errorf("Resolve() = %v, %v", observed.Path, observed.Query())
 	}
 }
@@ -0,0 +1,19 @@
+package misc
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+/* Generate a random UUID, version 4, see RFC 4122.
+Returns its canonical textual representation */
+func NewUUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+	//Version 4, variant RFC 4122
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
@@ -0,0 +1,22 @@
+package misc
+
+import (
+	"regexp"
+	"testing"
+)
+
+/* Nominal case, two well-formed and different UUIDs */
+func TestNewUUIDNominal(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := NewUUID()
+	if err != nil {
+		t.Fatalf("NewUUID() unexpected error %v", err)
+	}
+	second, _ := NewUUID()
+	if !pattern.MatchString(first) {
+		t.Errorf("NewUUID() = %v, not a version 4 UUID", first)
+	}
+	if first == second {
+		t.Errorf("NewUUID() = %v twice", first)
+	}
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A bare bone mock clock whose time only passes when sleeping, without actually waiting
+type Clock struct {
+	// Current time of the clock
+	Current time.Time
+	// Every delay slept so far
+	Sleeps []time.Duration
+	mutex  sync.Mutex
+}
+
+func (m *Clock) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.Current
+}
+
+func (m *Clock) Sleep(ctx context.Context, delay time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.Sleeps = append(m.Sleeps, delay)
+	m.Current = m.Current.Add(delay)
+	return nil
+}
@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"net/http"
+	netUrl "net/url"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/retriers"
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+/* Settings of a single request, overriding the ones of the resource */
+type requestOptions struct {
+	//Media type the request body is encoded into, the one of the marshaller if empty
+	encoding string
+	//Delay before reconnecting to an event stream, until the server advises one, the default one if 0
+	reconnectionDelay time.Duration
+	//Headers overriding the computed and the default ones
+	header http.Header
+	//Query parameters overriding the ones of the URL
+	query netUrl.Values
+	//Overall timeout of the call, the one of the resource if nil
+	timeout *time.Duration
+	//Request and response (un)marshaller, the one of the resource if nil
+	marshaller serializers.Marshaller
+	//Retry handler, the one of the resource if nil
+	retrier retriers.Retrier
+	//Accepted media ranges, the ones of the marshaller if empty
+	accept []string
+}
+
+/* Customize a single request, see Request() */
+type RequestOption func(options *requestOptions)
+
+/* Encode the request body into a specific media type, which must be supported by the marshaller of the resource */
+func WithEncoding(mediaType string) RequestOption {
+	return func(options *requestOptions) {
+		options.encoding = mediaType
+	}
+}
+
+/* Wait for a specific delay before reconnecting to an event stream, until the server advises one, see Subscribe() */
+func WithReconnectionDelay(delay time.Duration) RequestOption {
+	return func(options *requestOptions) {
+		options.reconnectionDelay = delay
+	}
+}
+
+/* Set a header, replacing the one computed for the request, e.g. Accept, or the default one of the resource */
+func WithHeader(key string, value string) RequestOption {
+	return func(options *requestOptions) {
+		if options.header == nil {
+			options.header = http.Header{}
+		}
+		options.header.Set(key, value)
+	}
+}
+
+/* Set a query parameter, replacing the one of the resolved URL */
+func WithQuery(key string, values ...string) RequestOption {
+	return func(options *requestOptions) {
+		if options.query == nil {
+			options.query = netUrl.Values{}
+		}
+		options.query[key] = values
+	}
+}
+
+/* Override the overall timeout of the resource for this call, all tries included, 0 means no limit */
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(options *requestOptions) {
+		options.timeout = &timeout
+	}
+}
+
+/* Override the marshaller of the resource for this call, in order to encode the request and decode the response */
+func WithMarshaller(marshaller serializers.Marshaller) RequestOption {
+	return func(options *requestOptions) {
+		options.marshaller = marshaller
+	}
+}
+
+/* Override the retrier of the resource for this call */
+func WithRetrier(retrier retriers.Retrier) RequestOption {
+	return func(options *requestOptions) {
+		options.retrier = retrier
+	}
+}
+
+/* Accept the given media ranges rather than the ones the marshaller can decode, see WithHeader() to set the Accept header as is */
+func WithAccept(mediaRanges ...string) RequestOption {
+	return func(options *requestOptions) {
+		options.accept = mediaRanges
+	}
+}
+
+/* Apply the options in order, the last one prevails.
+Returns the resulting settings */
+func newRequestOptions(options []RequestOption) *requestOptions {
+	settings := &requestOptions{}
+	for _, option := range options {
+		if option != nil {
+			option(settings)
+		}
+	}
+	return settings
+}
+
+/* The resource as customized by the options of a single call.
+Returns the resource itself if none of its settings is overridden */
+func (resource *resource) override(options *requestOptions) *resource {
+	if options.timeout == nil && options.marshaller == nil && options.retrier == nil {
+		return resource
+	}
+	custom := *resource
+	if options.timeout != nil {
+		custom.timeout = *options.timeout
+	}
+	if options.marshaller != nil {
+		custom.marshaller = options.marshaller
+	}
+	if options.retrier != nil {
+		custom.retrier = options.retrier
+	}
+	return &custom
+}
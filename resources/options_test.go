@@ -0,0 +1,175 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+/* Marshaller of plain text, for test purposes only */
+type textMarshaller struct{}
+
+func (marshaller *textMarshaller) Serialize(input interface{}) ([]byte, error) {
+	return []byte(*input.(*string)), nil
+}
+
+func (marshaller *textMarshaller) SerializationCompatibleMimetype() string {
+	return "text/plain"
+}
+
+func (marshaller *textMarshaller) Deserialize(input []byte) (interface{}, error) {
+	return string(input), nil
+}
+
+func (marshaller *textMarshaller) DeserializationCompatibleMimetypes() []string {
+	return []string{"text/plain"}
+}
+
+/* Nominal case, the request is encoded in the chosen format, and the response decoded according to its content type */
+func TestWithEncodingNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observed, expected := req.Header.Get("Accept"), "application/vnd.api+json, application/json, text/plain"; observed != expected {
+			t.Errorf("Request() Accept = %v, want %v", observed, expected)
+		}
+		if observed := req.Header.Get("Content-Type"); observed != "text/plain" {
+			t.Errorf("Request() Content-Type = %v, want text/plain", observed)
+		}
+		body, _ := io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader("pong " + string(body))),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithMarshaller(serializers.NewRegistry(serializers.NewJsonMarshaller(), &textMarshaller{}))
+
+	ping := "ping"
+	call, _, err := res.Request("POST", nil, &ping, WithEncoding("text/plain"))
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	body, _, err := call()
+	if err != nil || body != "pong ping" {
+		t.Errorf("call() = %v, %v", body, err)
+	}
+
+	typedCall, _, err := Do[string, string](res, "POST", nil, &ping, WithEncoding("text/plain"))
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	typedBody, _, err := typedCall()
+	if err != nil || *typedBody != "pong ping" {
+		t.Errorf("call() = %v, %v", typedBody, err)
+	}
+}
+
+/* Error case, the chosen format is not supported */
+func TestWithEncodingError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	res := NewResource(url)
+
+	body := map[string]string{"name": "julien"}
+	if _, _, err := res.Request("POST", nil, body, WithEncoding("text/plain")); !errors.Is(err, serializers.ErrNoMarshaller) {
+		t.Errorf("Request() = %v, want %v", err, serializers.ErrNoMarshaller)
+	}
+}
+
+/* Nominal case, the default headers of the resource prevail over the computed ones, and the options of the call over both */
+func TestRequestHeadersNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info?page=1&size=10")
+	mockClient := mocks.Client{}
+	var requests []*http.Request
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		statusCode := 200
+		if len(requests) == 1 {
+			statusCode = 503
+		}
+		return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).WithRequestID(true).
+		WithDefaultHeader("User-Agent", "exp_http_client/1.0").
+		WithDefaultHeader("X-Tenant", "default")
+
+	call, _, err := res.Request("GET", nil, nil, WithHeader("X-Tenant", "julien"), WithQuery("page", "2"), WithAccept("application/vnd.api+json"))
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	call()
+	call, _, _ = res.WithDefaultHeader("Accept", "application/json").Request("GET", nil, nil)
+	call()
+
+	if len(requests) != 3 {
+		t.Fatalf("Request() sent %v requests, want 3", len(requests))
+	}
+	first, retry, other := requests[0], requests[1], requests[2]
+	if first.Header.Get("User-Agent") != "exp_http_client/1.0" || first.Header.Get("X-Tenant") != "julien" || first.Header.Get("Accept") != "application/vnd.api+json" {
+		t.Errorf("Request() headers = %v", first.Header)
+	}
+	if first.URL.RawQuery != "page=2&size=10" {
+		t.Errorf("Request() query = %v", first.URL.RawQuery)
+	}
+	if id := first.Header.Get("X-Request-Id"); len(id) == 0 || retry.Header.Get("X-Request-Id") != id || other.Header.Get("X-Request-Id") == id {
+		t.Errorf("Request() X-Request-Id = %v, %v, %v", id, retry.Header.Get("X-Request-Id"), other.Header.Get("X-Request-Id"))
+	}
+	if other.Header.Get("X-Tenant") != "default" || other.Header.Get("Accept") != "application/json" || other.URL.RawQuery != "page=1&size=10" {
+		t.Errorf("Request() headers = %v, query = %v", other.Header, other.URL.RawQuery)
+	}
+}
+
+/* Nominal case, the timeout, the retrier and the marshaller of the resource are overridden for a single call */
+func TestRequestOverridesNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	tries := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		tries++
+		switch req.Method {
+		case "GET":
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		case "DELETE":
+			return &http.Response{StatusCode: 503, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		if observed := req.Header.Get("Content-Type"); observed != "text/plain" {
+			t.Errorf("Request() Content-Type = %v, want text/plain", observed)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("pong")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(time.Minute)
+
+	call, _, _ := res.Request("GET", nil, nil, WithTimeout(10*time.Millisecond), WithRetrier(instantRetrier()))
+	if _, _, err := call(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("call() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	tries = 0
+	call, _, _ = res.Request("DELETE", nil, nil, WithRetrier(instantRetrier()))
+	if _, statusCode, _ := call(); statusCode != 503 || tries != 3 {
+		t.Errorf("call() = %v after %v tries", statusCode, tries)
+	}
+
+	ping := "ping"
+	call, _, _ = res.Request("POST", nil, &ping, WithMarshaller(&textMarshaller{}))
+	if body, _, err := call(); err != nil || body != "pong" {
+		t.Errorf("call() = %v, %v", body, err)
+	}
+
+	if res.timeout != time.Minute || res.marshaller.SerializationCompatibleMimetype() != "application/vnd.api+json" {
+		t.Errorf("Request() modified the resource %v, %v", res.timeout, res.marshaller)
+	}
+}
@@ -0,0 +1,31 @@
+package resources
+
+import (
+	"github.com/okayawright/exp_http_client/resources/limiters"
+)
+
+/* Throttle every request sent by this resource, retries included.
+The same limiter can be shared by several resources, e.g. in order to share the quota of a host.
+Returns the updated resource */
+func (resource *resource) WithLimiter(limiter limiters.Limiter) *resource {
+	resource.limiter = limiter
+	return resource
+}
+
+/* Wait for the permission of the limiter before every attempt.
+If the limiter can tune itself, it observes every response received along with the request of the attempt */
+func limit(limiter limiters.Limiter) Middleware {
+	return func(next Handler) Handler {
+		return func(exchange *Exchange) error {
+			if err := limiter.Wait(exchange.Request); err != nil {
+				return err
+			}
+			request := exchange.Request
+			err := next(exchange)
+			if tuner, ok := limiter.(limiters.Tuner); ok && err == nil {
+				tuner.Observe(request, exchange.Response)
+			}
+			return err
+		}
+	}
+}
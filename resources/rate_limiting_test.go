@@ -0,0 +1,111 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/limiters"
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, every try waits for the limiter, which observes every response */
+func TestWithLimiterNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		statusCode := 200
+		header := http.Header{}
+		if pass == 1 {
+			statusCode = 429
+			header.Set("Retry-After", "2")
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := limiters.NewTokenBucket(10, 10).WithClock(&clock).WithAutoTune(true)
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).WithLimiter(limiter)
+
+	call, _, _ := res.Request("GET", nil, nil)
+	_, code, err := call()
+	if err != nil || code != 200 || pass != 2 {
+		t.Fatalf("call() = %v, %v after %v tries", code, err, pass)
+	}
+	//The retrier itself doesn't wait with the mocked clock, the limiter does
+	if len(clock.Sleeps) != 1 || clock.Sleeps[0] != 2*time.Second {
+		t.Errorf("WithLimiter() slept %v, want [2s]", clock.Sleeps)
+	}
+}
+
+/* Nominal case, a limiter per host is tuned by the responses of its host, even though they do not carry their request */
+func TestWithLimiterPerHostNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		statusCode := 200
+		header := http.Header{}
+		if pass == 1 {
+			statusCode = 429
+			header.Set("Retry-After", "2")
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	limiter := limiters.NewPerHost(func() limiters.Limiter {
+		return limiters.NewTokenBucket(10, 10).WithClock(&clock).WithAutoTune(true)
+	})
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).WithLimiter(limiter)
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if _, code, err := call(); err != nil || code != 200 || pass != 2 {
+		t.Fatalf("call() = %v, %v after %v tries", code, err, pass)
+	}
+	if len(clock.Sleeps) != 1 || clock.Sleeps[0] != 2*time.Second {
+		t.Errorf("WithLimiter() slept %v, want [2s]", clock.Sleeps)
+	}
+}
+
+/* Error case, the request is rejected before reaching the HTTP client */
+func TestWithLimiterError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	//Shared by two resources
+	limiter := limiters.NewPerHost(func() limiters.Limiter {
+		return limiters.NewTokenBucket(0.01, 1).WithBlocking(false)
+	})
+	first := NewResource(url).WithClient(&mockClient).WithLimiter(limiter)
+	second := NewResource(url).WithClient(&mockClient).WithLimiter(limiter)
+
+	call, _, _ := first.Request("GET", nil, nil)
+	if _, _, err := call(); err != nil {
+		t.Fatalf("call() unexpected error %v", err)
+	}
+	call, _, _ = second.Request("GET", nil, nil)
+	if _, _, err := call(); !errors.Is(err, limiters.ErrRateLimitExceeded) || pass != 1 {
+		t.Errorf("call() = %v after %v tries, want %v", err, pass, limiters.ErrRateLimitExceeded)
+	}
+}
@@ -4,20 +4,27 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	netUrl "net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/okayawright/exp_http_client/resources/auth"
+	"github.com/okayawright/exp_http_client/resources/limiters"
 	"github.com/okayawright/exp_http_client/resources/misc"
 	"github.com/okayawright/exp_http_client/resources/retriers"
 	"github.com/okayawright/exp_http_client/resources/serializers"
 )
 
-// default request timeout if unspecified, in seconds
-const defaultTimeout = 30
+// default request timeout if unspecified
+const defaultTimeout = 30 * time.Second
+
+// default media type of the raw request bodies
+const defaultRawContentType = "application/octet-stream"
 
 /* A NewJsonMarshaller() is a re-usable and configurable HTTP REST client tied to a specific endpoint and a serializer */
 type resource struct {
@@ -29,8 +36,26 @@ type resource struct {
 	marshaller serializers.Marshaller
 	//Retry handler
 	retrier retriers.Retrier
-	//Request timeout in seconds, 0 means no limit
-	timeout uint
+	//Overall timeout of a call, all tries included, 0 means no limit
+	timeout time.Duration
+	//Timeout of every single try, 0 means no limit other than the overall one
+	attemptTimeout time.Duration
+	//Report unsuccessful HTTP statuses as HTTPError
+	httpErrors bool
+	//Middlewares executed around every attempt
+	middlewares []Middleware
+	//Optional credentials provider
+	authenticator auth.Authenticator
+	//Optional throttling of the outgoing requests
+	limiter limiters.Limiter
+	//Attach a generated Idempotency-Key header to the non-idempotent requests
+	idempotencyKey bool
+	//Attach a generated X-Request-Id header to every call
+	requestID bool
+	//Headers sent with every call, overriding the computed ones
+	headers http.Header
+	//Maximum size of a response body read in memory, 0 means no limit
+	maxResponseSize int64
 }
 
 /* Make an HTTP request for a prepared Request.
@@ -46,13 +71,63 @@ func (resource *resource) WithMarshaller(marshaller serializers.Marshaller) *res
 	return resource
 }
 
-/* Use a specific marshaller/unmarshaller for this resource.
+/* Set the overall timeout of every call made to this resource, all tries included, 0 means no limit.
 Returns the updated resource */
-func (resource *resource) WithTimeout(timeout uint) *resource {
+func (resource *resource) WithTimeout(timeout time.Duration) *resource {
 	resource.timeout = timeout
 	return resource
 }
 
+/* Set the timeout of every single try made to this resource, 0 means no limit other than the overall one.
+A try that times out can then be retried as long as the overall timeout is not reached.
+Returns the updated resource */
+func (resource *resource) WithAttemptTimeout(attemptTimeout time.Duration) *resource {
+	resource.attemptTimeout = attemptTimeout
+	return resource
+}
+
+/* Report the responses with a non-2xx HTTP status code as an HTTPError instead of a regular body.
+Returns the updated resource */
+func (resource *resource) WithHTTPErrors(httpErrors bool) *resource {
+	resource.httpErrors = httpErrors
+	return resource
+}
+
+/* Attach a generated Idempotency-Key header to the non-idempotent requests, e.g. POST or PATCH.
+A new key is generated for every call and reused by all its retries, see retriers.NewIdempotentPolicy().WithIdempotencyKey(true) to allow such retries.
+Returns the updated resource */
+func (resource *resource) WithIdempotencyKey(idempotencyKey bool) *resource {
+	resource.idempotencyKey = idempotencyKey
+	return resource
+}
+
+/* Attach a generated X-Request-Id header to every call, unless one is already set.
+A new identifier is generated for every call and reused by all its retries.
+Returns the updated resource */
+func (resource *resource) WithRequestID(requestID bool) *resource {
+	resource.requestID = requestID
+	return resource
+}
+
+/* Send a header with every call made to this resource, e.g. User-Agent.
+It overrides the headers computed for a request, e.g. Accept, and can be overridden for a single call with the WithHeader() option.
+Returns the updated resource */
+func (resource *resource) WithDefaultHeader(key string, value string) *resource {
+	if resource.headers == nil {
+		resource.headers = http.Header{}
+	}
+	resource.headers.Set(key, value)
+	return resource
+}
+
+/* Set the maximum size, in bytes, of a response body read in memory, 0 means no limit.
+A larger body fails the call with ErrResponseTooLarge, the streamed bodies are not limited.
+Returns the updated resource */
+func (resource *resource) WithMaxResponseSize(maxResponseSize int64) *resource {
+	resource.maxResponseSize = maxResponseSize
+	return resource
+}
+
 /* Use a specific HTTP client for this resource.
 Returns the updated resource */
 func (resource *resource) WithClient(client misc.HttpClient) *resource {
@@ -90,89 +165,190 @@ func NewResource(endpoint *netUrl.URL) *resource {
 	}
 }
 
+/* Is the body sent as is, without being serialized */
+func isRaw(body interface{}) bool {
+	_, ok := body.(io.Reader)
+	return ok
+}
+
+/* Is the body a form, which carries its own encoding regardless of the marshaller */
+func isForm(body interface{}) bool {
+	switch body.(type) {
+	case netUrl.Values, *netUrl.Values, *serializers.Multipart:
+		return true
+	default:
+		return false
+	}
+}
+
 /* encode the body if needed, using the provided marshaller.
+url.Values are encoded as application/x-www-form-urlencoded, a serializers.Multipart is streamed as multipart/form-data, and an io.Reader is streamed as is.
 Returns the encoding MIME type, and the actual serialized body */
 func encodeRequestBody(body interface{}, marshaller serializers.Marshaller) (string, io.Reader, error) {
-	if body != nil {
-		//TODO right now we only handle JSON structured REST APIs
-		encodedBody, err := marshaller.Serialize(body)
-		if err != nil {
-			return "", nil, err
-		}
-		return marshaller.SerializationCompatibleMimetype(), bytes.NewBuffer(encodedBody), nil
-	} else {
+	switch typedBody := body.(type) {
+	case nil:
 		return "", nil, nil
+	case netUrl.Values, *netUrl.Values:
+		marshaller = serializers.NewFormMarshaller()
+	case *serializers.Multipart:
+		return typedBody.ContentType(), typedBody.Reader(), nil
+	case io.Reader:
+		return defaultRawContentType, typedBody, nil
 	}
+	encodedBody, err := marshaller.Serialize(body)
+	if err != nil {
+		return "", nil, err
+	}
+	return marshaller.SerializationCompatibleMimetype(), bytes.NewBuffer(encodedBody), nil
 }
 
 /*
 Prepare a request for a given action, with optional values for named parameters within the URL and the body struct if required.
 actionName is the case-sensitive name of a registered action on this resource, an undefined action is fatal,
-urlParameters is an optional set of named parameters values to replace within the url to call,
-body is the optional body to send in the request, only meaningful for verbs that usually send request bodies (e.g. POST, PUT, PATCH).
+urlParameters is an optional set of named parameters values to replace within the url to call, either a map[string]string or a struct whose fields are tagged with url:"name,omitempty" for the variables of the template, or query:"name,omitempty,format" for the query parameters, format being a misc.ArrayFormat,
+body is the optional body to send in the request, only meaningful for verbs that usually send request bodies (e.g. POST, PUT, PATCH),
+options optionally customize this request, e.g. WithEncoding().
 Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function
 */
-func (resource *resource) Request(verb string, urlParameters *map[string]string, body interface{}) (CallFunc, context.CancelFunc, error) {
+func (resource *resource) Request(verb string, urlParameters interface{}, body interface{}, options ...RequestOption) (CallFunc, context.CancelFunc, error) {
+	return resource.RequestContext(context.Background(), verb, urlParameters, body, options...)
+}
 
-	//Clone a new pristine context in order to control the request once sent
-	//and make the request cancellable and expirable
-	actualContext, cancel := context.WithTimeout(context.Background(), time.Duration(resource.timeout)*time.Second)
+/*
+Prepare a request for a given action bound to the caller context, see Request().
+ctx is the parent context of the request, its deadline, cancellation and values are propagated to every call and retry, the resource timeout is layered on top of it.
+Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function
+*/
+func (resource *resource) RequestContext(ctx context.Context, verb string, urlParameters interface{}, body interface{}, options ...RequestOption) (CallFunc, context.CancelFunc, error) {
+
+	settings := newRequestOptions(options)
+	actual := resource.override(settings)
+	request, cancel, err := actual.prepare(ctx, verb, urlParameters, body, settings)
+	if err != nil {
+		return nil, cancel, err
+	}
+
+	return func() (interface{}, int, error) {
+		return actual.call(request)
+	}, cancel, nil
+
+}
+
+/* Build the HTTP request shared by all the calls of a given action, see Request().
+Returns the prepared request, and a request cancelling function */
+func (resource *resource) prepare(ctx context.Context, verb string, urlParameters interface{}, body interface{}, options *requestOptions) (*http.Request, context.CancelFunc, error) {
+
+	//Derive a new context from the caller's one in order to control the request once sent
+	//and make the request cancellable, it will be made expirable for every call
+	actualContext, cancel := context.WithCancel(ctx)
 
 	//Resolve the template URL if needed
-	url := misc.Resolve(resource.endpoint, urlParameters)
+	url, err := misc.Resolve(resource.endpoint, urlParameters)
+	if err != nil {
+		return nil, cancel, err
+	}
 	//Shouldn't happen, panic
 	if url == nil {
 		panic("The endpoint to query cannot be nil")
 	}
+	if len(options.query) > 0 {
+		query := url.Query()
+		for key, values := range options.query {
+			query[key] = values
+		}
+		url.RawQuery = query.Encode()
+	}
 
-	//Prepare the body, if needed
-	contentType, encodedBodyReader, err := encodeRequestBody(body, resource.marshaller)
+	//Prepare the body, if needed, in the requested format unless it carries its own
+	encoder := resource.marshaller
+	if !isForm(body) && !isRaw(body) {
+		encoder, err = serializers.Encoder(resource.marshaller, options.encoding)
+		//Any body can be sent as a form, e.g. a struct with form tags, even if the marshaller cannot encode it
+		if errors.Is(err, serializers.ErrNoMarshaller) {
+			encoder, err = serializers.Encoder(serializers.NewFormMarshaller(), options.encoding)
+		}
+		if err != nil {
+			return nil, cancel, err
+		}
+	}
+	contentType, encodedBodyReader, err := encodeRequestBody(body, encoder)
 	if err != nil {
 		return nil, cancel, err
 	}
+	//A raw body is of the requested media type
+	if len(contentType) > 0 && len(options.encoding) > 0 && !isForm(body) {
+		contentType = options.encoding
+	}
 
 	request, err := http.NewRequestWithContext(actualContext, strings.ToUpper(verb), url.String(), encodedBodyReader)
 	//Rethrow without doing anything if an error occurs, we do not know what to do with it right here
 	if err != nil {
 		return nil, cancel, err
 	}
+	//A streamed multipart body is rebuilt for every send, if possible
+	if multipart, ok := body.(*serializers.Multipart); ok && multipart.Replayable() {
+		request.GetBody = func() (io.ReadCloser, error) {
+			return multipart.Reader(), nil
+		}
+	}
+	//Otherwise a streamed body can only be sent by a single call
+	if !misc.Replayable(request) {
+		request.Body = &oneShotBody{ReadCloser: request.Body}
+	}
 
 	//Augment the request with metadata if needed
 	if len(contentType) > 0 {
 		request.Header.Set("Content-Type", contentType)
 	}
-	//TODO right now we only handle JSON structured REST APIs
-	request.Header.Set("Accept", strings.Join(resource.marshaller.DeserializationCompatibleMimetypes(), ","))
+	mediaRanges := resource.marshaller.DeserializationCompatibleMimetypes()
+	if len(options.accept) > 0 {
+		mediaRanges = options.accept
+	}
+	accept, err := serializers.FormatAccept(mediaRanges)
+	if err != nil {
+		return nil, cancel, err
+	}
+	request.Header.Set("Accept", accept)
 
-	return func() (interface{}, int, error) {
-		return call(resource.client, request, &resource.marshaller, &resource.retrier)
-	}, cancel, nil
+	//The default headers of the resource prevail over the computed ones, and the ones of the call over both
+	for _, headers := range []http.Header{resource.headers, options.header} {
+		for key, values := range headers {
+			request.Header[key] = append([]string(nil), values...)
+		}
+	}
 
+	return request, cancel, nil
 }
 
-/* decode the body if needed, using thcalle provided marshaller, if compatible.
-contentTypes are the optional MIME types returned in the response and are verified for compatibility,
-Returns the actual serialized body */
-func decodeResponseBody(rawBody io.Reader, contentTypes []string, marshaller serializers.Marshaller) (interface{}, error) {
+/* read the raw body and find the marshaller compatible with it.
+contentTypes are the optional MIME types returned in the response, the first one must be supported by the provided marshaller, or by one of the marshallers it delegates to,
+Returns the binary body, and the marshaller to decode it with */
+func readResponseBody(rawBody io.Reader, contentTypes []string, marshaller serializers.Marshaller) ([]byte, serializers.Marshaller, error) {
 	responseBody, err := ioutil.ReadAll(rawBody)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	//Just to be sure, we should check whether the API sent us back a format we understand
 	if contentTypes != nil && len(contentTypes) > 0 {
-		match := false
-		for _, s := range marshaller.DeserializationCompatibleMimetypes() {
-			if misc.Find(contentTypes, s, true) != -1 {
-				match = true
-				break
-			}
-		}
-		if !match {
-			return nil, errors.New("Cannot deserialize the response")
+		marshaller, err = serializers.Decoder(marshaller, contentTypes[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, strings.Join(contentTypes, ", "))
 		}
 	}
 
+	return responseBody, marshaller, nil
+}
+
+/* decode the body if needed, using the provided marshaller, if compatible.
+contentTypes are the optional MIME types returned in the response and are verified for compatibility,
+Returns the actual serialized body */
+func decodeResponseBody(rawBody io.Reader, contentTypes []string, marshaller serializers.Marshaller) (interface{}, error) {
+	responseBody, marshaller, err := readResponseBody(rawBody, contentTypes, marshaller)
+	if err != nil {
+		return nil, err
+	}
+
 	//Transform the binary body into a structured map, if not empty
 	if len(responseBody) > 0 {
 		return marshaller.Deserialize(responseBody)
@@ -181,21 +357,204 @@ func decodeResponseBody(rawBody io.Reader, contentTypes []string, marshaller ser
 	}
 }
 
-/* Make an HTTP request with the given client for the specified prepared request.
-Returns the structured map corresponding to the response body, the HTTP status code, 0 means we don't have one to provide */
-func call(client misc.HttpClient, request *http.Request, marshaller *serializers.Marshaller, retrier *retriers.Retrier) (interface{}, int, error) {
+/* decode the body if needed into the given struct pointer, using the provided marshaller, if compatible.
+contentTypes are the optional MIME types returned in the response and are verified for compatibility,
+output is left untouched if the body is empty */
+func decodeResponseBodyInto(rawBody io.Reader, contentTypes []string, marshaller serializers.Marshaller, output interface{}) error {
+	responseBody, marshaller, err := readResponseBody(rawBody, contentTypes, marshaller)
+	if err != nil {
+		return err
+	}
+
+	if len(responseBody) > 0 {
+		return serializers.DeserializeInto(marshaller, responseBody, output)
+	}
+	return nil
+}
+
+/* Set a header to a newly generated UUID */
+func setUUID(header http.Header, key string) error {
+	value, err := misc.NewUUID()
+	if err != nil {
+		return err
+	}
+	header.Set(key, value)
+	return nil
+}
+
+/* Send the prepared request with the resource retrier.
+timeout is the overall timeout of the call, which lasts until the response body is closed, and attemptTimeout the one of every try, 0 meaning no limit.
+streamed tells whether the response body is read as it comes rather than in memory, in which case the middlewares cannot decode it.
+Returns the response, whose body must be closed, and the actual number of tries */
+func (resource *resource) send(request *http.Request, timeout time.Duration, attemptTimeout time.Duration, streamed bool) (*http.Response, uint, error) {
+	//A prepared request whose body cannot be rebuilt cannot be sent twice
+	if body, ok := request.Body.(*oneShotBody); ok && !atomic.CompareAndSwapInt32(&body.sent, 0, 1) {
+		return nil, 0, misc.ErrBodyNotReplayable
+	}
+
+	//The same identifiers must be sent with all the tries of this call
+	withKey := resource.idempotencyKey && !retriers.IsIdempotent(request.Method) && len(request.Header.Get("Idempotency-Key")) == 0
+	withID := resource.requestID && len(request.Header.Get("X-Request-Id")) == 0
+	if withKey || withID {
+		request = request.Clone(request.Context())
+		if withKey {
+			if err := setUUID(request.Header, "Idempotency-Key"); err != nil {
+				return nil, 0, err
+			}
+		}
+		if withID {
+			if err := setUUID(request.Header, "X-Request-Id"); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	//The overall timeout starts with the call, not when the request is prepared
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(request.Context(), timeout)
+		request = request.WithContext(ctx)
+	}
 
 	//Actual HTTP request
-	response, _, err := (*retrier).Try(client, request)
+	response, tries, err := resource.retrier.Try(newPipeline(resource.handler(), resource.marshaller, attemptTimeout).withBody(resource.maxResponseSize, streamed), request)
+	if err != nil || response == nil || response.Body == nil {
+		cancel()
+		misc.Discard(response)
+		return nil, tries, err
+	}
+	misc.CancelOnClose(response, cancel)
+	return response, tries, nil
+}
+
+/* Send the prepared request for a response lasting as long as needed, e.g. an event stream or an upgraded connection.
+The timeout of the resource only applies until the response headers are received, there is no timeout per try.
+Returns the response, whose body must be closed, and the actual number of tries */
+func (resource *resource) open(request *http.Request) (*http.Response, uint, error) {
+	ctx, cancel := context.WithCancel(request.Context())
+	var timer *time.Timer
+	if resource.timeout > 0 {
+		timer = time.AfterFunc(resource.timeout, cancel)
+	}
+	response, tries, err := resource.send(request.WithContext(ctx), 0, 0, true)
 	if err != nil {
-		return nil, 0, err
+		cancel()
+		return nil, tries, err
+	}
+	misc.CancelOnClose(response, cancel)
+	//The timeout may have expired right after the headers were received
+	if timer != nil && !timer.Stop() {
+		response.Body.Close()
+		return nil, tries, ctx.Err()
+	}
+	return response, tries, nil
+}
+
+/* Request body that cannot be rebuilt, which is handed over to the first call only */
+type oneShotBody struct {
+	io.ReadCloser
+	//Has a call already sent the body, 1 if so
+	sent int32
+}
+
+/* Guard the reading of a response body in memory against a maximum response size, 0 means no limit.
+Returns the guarded body, or ErrResponseTooLarge right away if the announced length is already too large */
+func limitBody(response *http.Response, maxResponseSize int64) (io.Reader, error) {
+	if maxResponseSize <= 0 {
+		return response.Body, nil
+	}
+	if response.ContentLength > maxResponseSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, response.ContentLength)
+	}
+	return &limitedBody{reader: response.Body, remaining: maxResponseSize}, nil
+}
+
+/* Body reader failing with ErrResponseTooLarge once more than a given number of bytes is read */
+type limitedBody struct {
+	reader io.Reader
+	//Bytes that can still be read
+	remaining int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	//Read one byte more than allowed in order to detect an overflow
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+	n, err := body.reader.Read(p)
+	body.remaining -= int64(n)
+	if body.remaining < 0 {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+/* Read the body of an unsuccessful response within limits, then close it.
+Returns the matching HTTPError */
+func (resource *resource) readHTTPError(request *http.Request, response *http.Response, tries uint) error {
+	defer response.Body.Close()
+	body, err := limitBody(response, resource.maxResponseSize)
+	if err != nil {
+		return err
+	}
+	rawBody, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return newHTTPError(request, response, rawBody, tries, resource.marshaller)
+}
+
+/* Make an HTTP request with the resource client for the specified prepared request.
+decode is called to deserialize the response body unless the resource reports the response status as an HTTPError.
+Returns the HTTP status code, 0 means we don't have one to provide */
+func (resource *resource) exchange(request *http.Request, decode func(rawBody io.Reader, contentTypes []string) error) (int, error) {
+
+	response, tries, err := resource.send(request, resource.timeout, resource.attemptTimeout, false)
+	if err != nil {
+		return 0, err
 	}
 
-	//Deserialize the response body
 	//In order to reuse a TCP connection from the pool you need to read the response body till the end @see https://golang.cafe/blog/how-to-reuse-http-connections-in-go.html
 	defer response.Body.Close()
-	bodyStruct, err := decodeResponseBody(response.Body, response.Header["Content-Type"], *marshaller)
 
-	return bodyStruct, response.StatusCode, err
+	//The whole body is read in memory, within limits
+	body, err := limitBody(response, resource.maxResponseSize)
+	if err != nil {
+		return response.StatusCode, err
+	}
+
+	//Unsuccessful responses carry an error payload, not the expected body
+	if resource.httpErrors && !isSuccessful(response.StatusCode) {
+		rawBody, err := ioutil.ReadAll(body)
+		if err != nil {
+			return response.StatusCode, err
+		}
+		return response.StatusCode, newHTTPError(request, response, rawBody, tries, resource.marshaller)
+	}
+
+	//Deserialize the response body
+	return response.StatusCode, decode(body, response.Header["Content-Type"])
+
+}
+
+/* Make an HTTP request with the resource client for the specified prepared request.
+Returns the structured map corresponding to the response body, the HTTP status code, 0 means we don't have one to provide */
+func (resource *resource) call(request *http.Request) (interface{}, int, error) {
+	var bodyStruct interface{}
+	statusCode, err := resource.exchange(request, func(rawBody io.Reader, contentTypes []string) error {
+		var err error
+		bodyStruct, err = decodeResponseBody(rawBody, contentTypes, resource.marshaller)
+		return err
+	})
+	return bodyStruct, statusCode, err
+}
 
+/* Make an HTTP request with the resource client for the specified prepared request, see call().
+The response body is decoded straight into output.
+Returns the HTTP status code, 0 means we don't have one to provide */
+func (resource *resource) callInto(request *http.Request, output interface{}) (int, error) {
+	return resource.exchange(request, func(rawBody io.Reader, contentTypes []string) error {
+		return decodeResponseBodyInto(rawBody, contentTypes, resource.marshaller, output)
+	})
 }
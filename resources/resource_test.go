@@ -2,6 +2,8 @@ package resources
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	netUrl "net/url"
@@ -11,14 +13,21 @@ import (
 	"time"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/okayawright/exp_http_client/resources/misc"
 	"github.com/okayawright/exp_http_client/resources/mocks"
+	"github.com/okayawright/exp_http_client/resources/retriers"
 	"github.com/okayawright/exp_http_client/resources/serializers"
 )
 
+/* The default retrier, without actually waiting between each try */
+func instantRetrier() retriers.Retrier {
+	return retriers.NewExponentialRetrier().WithClock(&mocks.Clock{})
+}
+
 /* Nominal case, create a resource with a customized timeout */
 func TestNewCustomizedNominal(t *testing.T) {
 	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
-	var timeout uint = 10
+	timeout := 10 * time.Second
 
 	observed := NewResource(url).WithTimeout(timeout)
 	if url.String() != observed.endpoint.String() {
@@ -59,6 +68,43 @@ func TestRequestNominal(t *testing.T) {
 
 }
 
+/* Nominal case, the URL parameters are given as a tagged struct */
+func TestRequestStructParametersNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/{user}/info")
+	mockClient := mocks.Client{}
+	var observed string
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		observed = req.URL.String()
+		return &http.Response{StatusCode: 204, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := res.Request("GET", &struct {
+		User    string   `url:"user"`
+		Version int      `query:"version"`
+		Fields  []string `query:"fields,comma"`
+	}{User: "julien", Version: 2, Fields: []string{"name", "bio"}}, nil)
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	if _, _, err := call(); err != nil {
+		t.Fatalf("call() unexpected error %v", err)
+	}
+	if expected := "http://localhost:8080/api/julien/info?fields=name%2Cbio&version=2"; observed != expected {
+		t.Errorf("Request() = %v, want %v", observed, expected)
+	}
+}
+
+/* Error case, a required URL parameter is missing */
+func TestRequestParametersError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/{user}/info")
+	res := NewResource(url)
+
+	if _, _, err := res.Request("GET", nil, nil); !errors.Is(err, misc.ErrUnresolvedVariable) {
+		t.Errorf("Request() = %v, want %v", err, misc.ErrUnresolvedVariable)
+	}
+}
+
 /* Nominal case, if there's a body encode it */
 func TestResourceEncodeRequestBodyNominal(t *testing.T) {
 	type secret struct {
@@ -124,6 +170,64 @@ func TestResourceDecodeResponseBodyNominal(t *testing.T) {
 	}
 }
 
+/* Nominal case, the response content type is strictly matched against the ones of the marshaller */
+func TestResourceDecodeResponseBodyContentTypeNominal(t *testing.T) {
+	jsonMarshaller := serializers.NewJsonMarshaller()
+	for _, contentType := range []string{"application/json; charset=utf-8", "application/problem+json", "APPLICATION/VND.API+JSON"} {
+		if _, err := decodeResponseBody(strings.NewReader("{}"), []string{contentType}, jsonMarshaller); err != nil {
+			t.Errorf("decodeResponseBody(%v) unexpected error %v", contentType, err)
+		}
+	}
+}
+
+/* Nominal case, the response is converted according to the charset of its content type, even without an XML prolog */
+func TestResourceDecodeResponseBodyCharsetNominal(t *testing.T) {
+	type note struct {
+		Text string `xml:"text"`
+	}
+	var decoded note
+	err := decodeResponseBodyInto(strings.NewReader("<note><text>caf\xe9</text></note>"), []string{"text/xml; charset=ISO-8859-1"}, serializers.NewXmlMarshaller(), &decoded)
+	if err != nil {
+		t.Fatalf("decodeResponseBodyInto() unexpected error %v", err)
+	}
+	if decoded.Text != "café" {
+		t.Errorf("decodeResponseBodyInto() = %q, want %q", decoded.Text, "café")
+	}
+}
+
+/* Nominal case, a marshaller without DeserializeInto still decodes into the given pointer */
+func TestResourceDecodeResponseBodyIntoFallbackNominal(t *testing.T) {
+	var decoded string
+	err := decodeResponseBodyInto(strings.NewReader("caf\xe9"), []string{"text/plain; charset=ISO-8859-1"}, &textMarshaller{}, &decoded)
+	if err != nil || decoded != "café" {
+		t.Errorf("decodeResponseBodyInto() = %q, %v, want %q", decoded, err, "café")
+	}
+}
+
+/* Error case, the response content type only looks like one of the marshaller */
+func TestResourceDecodeResponseBodyContentTypeError(t *testing.T) {
+	jsonMarshaller := serializers.NewJsonMarshaller()
+	for _, contentType := range []string{"application/jsonp", "text/application/json", "application/json;;"} {
+		if _, err := decodeResponseBody(strings.NewReader("{}"), []string{contentType}, jsonMarshaller); !errors.Is(err, ErrUnsupportedMediaType) {
+			t.Errorf("decodeResponseBody(%v) = %v, want %v", contentType, err, ErrUnsupportedMediaType)
+		}
+	}
+}
+
+/* Nominal case, the Accept header lists the media ranges of the marshaller */
+func TestResourceAcceptNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	res := NewResource(url)
+
+	request, _, err := res.prepare(context.Background(), "GET", nil, nil, newRequestOptions(nil))
+	if err != nil {
+		t.Fatalf("prepare() unexpected error %v", err)
+	}
+	if observed, expected := request.Header.Get("Accept"), "application/vnd.api+json, application/json"; observed != expected {
+		t.Errorf("prepare() = %v, want %v", observed, expected)
+	}
+}
+
 /* Nominal case, call the REST API and get back a 200 */
 func TestResourceCallNominal(t *testing.T) {
 	type secret struct {
@@ -143,7 +247,7 @@ func TestResourceCallNominal(t *testing.T) {
 			Body:       io.NopCloser(strings.NewReader("{}")),
 		}, nil
 	}
-	res := NewResource(url).WithClient(&mockClient).WithTimeout(60)
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(60 * time.Second)
 
 	call, _, err := res.Request("GET", nil, nil)
 	if err != nil {
@@ -174,7 +278,7 @@ func TestResourceCancelNominal(t *testing.T) {
 		time.Sleep(10 * time.Second)
 		return &http.Response{}, nil
 	}
-	res := NewResource(url).WithClient(&mockClient).WithTimeout(60)
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(60 * time.Second)
 
 	call, cancel, err := res.Request("GET", nil, nil)
 	if err != nil {
@@ -191,3 +295,391 @@ func TestResourceCancelNominal(t *testing.T) {
 	cancel()
 	//If we're it means the request has been successfully cancelled on time
 }
+
+/* Nominal case, the cancellation of the caller context is propagated to the call */
+func TestResourceRequestContextCancelNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	// Wait for the request to be cancelled before sending back the error
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(60 * time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	call, _, err := res.RequestContext(ctx, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("RequestContext() unexpected error %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	_, observedStatusCode, err := call()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Call() unexpected error %v", err)
+	}
+	if observedStatusCode != 0 {
+		t.Errorf("Call() = %v, want %v", observedStatusCode, 0)
+	}
+}
+
+/* Nominal case, the values of the caller context are propagated to the call */
+func TestResourceRequestContextValuesNominal(t *testing.T) {
+	type key struct{}
+	expectedValue := "span-1"
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observedValue := req.Context().Value(key{}); observedValue != expectedValue {
+			t.Errorf("Call() context value = %v, want %v", observedValue, expectedValue)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := res.RequestContext(context.WithValue(context.Background(), key{}, expectedValue), "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("RequestContext() unexpected error %v", err)
+	}
+	if _, _, err = call(); err != nil {
+		t.Errorf("Call() unexpected error %v", err)
+	}
+}
+
+/* Nominal case, the same prepared request can be called several times with its body */
+func TestResourceCallTwiceNominal(t *testing.T) {
+	expectedBody := `{"token":"zufeb5e1b6e1b6eb"}`
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		observedBody, _ := io.ReadAll(req.Body)
+		if string(observedBody) != expectedBody {
+			t.Errorf("Call() body = %v, want %v", string(observedBody), expectedBody)
+		}
+		return &http.Response{
+			StatusCode: 201,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := res.Request("POST", nil, map[string]string{"token": "zufeb5e1b6e1b6eb"})
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := call(); err != nil {
+			t.Fatalf("Call() unexpected error %v", err)
+		}
+	}
+}
+
+/* Nominal case, the same idempotency key is sent with all the tries of a call, and a new one for the next call */
+func TestWithIdempotencyKeyNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	var keys []string
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		statusCode := 201
+		if len(keys) == 1 {
+			statusCode = 503
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	retrier := retriers.NewExponentialRetrier().WithClock(&mocks.Clock{}).WithPolicy(retriers.NewIdempotentPolicy().WithIdempotencyKey(true))
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(retrier).WithIdempotencyKey(true)
+
+	call, _, _ := res.Request("POST", nil, map[string]string{"token": "zufeb5e1b6e1b6eb"})
+	for i := 0; i < 2; i++ {
+		if _, _, err := call(); err != nil {
+			t.Fatalf("Call() unexpected error %v", err)
+		}
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Call() attempts = %v, want %v", len(keys), 3)
+	}
+	if len(keys[0]) == 0 || keys[0] != keys[1] {
+		t.Errorf("Call() keys of the first call = %v, %v", keys[0], keys[1])
+	}
+	if len(keys[2]) == 0 || keys[2] == keys[0] {
+		t.Errorf("Call() key of the second call = %v", keys[2])
+	}
+}
+
+/* Nominal case, no idempotency key for idempotent verbs */
+func TestWithIdempotencyKeyIdempotentNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if key := req.Header.Get("Idempotency-Key"); len(key) > 0 {
+			t.Errorf("Call() unexpected key %v", key)
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithIdempotencyKey(true)
+
+	call, _, _ := res.Request("PUT", nil, map[string]string{"token": "zufeb5e1b6e1b6eb"})
+	if _, _, err := call(); err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+}
+
+/* Nominal case, a try that times out is retried within the overall timeout */
+func TestResourceAttemptTimeoutNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	// Hang on the first try until it times out, then operate back to normal
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		if pass == 1 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier()).WithTimeout(5 * time.Second).WithAttemptTimeout(100 * time.Millisecond)
+
+	call, _, _ := res.Request("GET", nil, nil)
+	_, observedStatusCode, err := call()
+	if err != nil {
+		t.Fatalf("Call() unexpected error %v", err)
+	}
+	if observedStatusCode != 200 || pass != 2 {
+		t.Errorf("Call() = %v after %v tries, want %v after %v tries", observedStatusCode, pass, 200, 2)
+	}
+}
+
+/* Nominal case, the overall timeout starts with every call, not when the request is prepared */
+func TestResourceTimeoutPerCallNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithTimeout(150 * time.Millisecond)
+
+	call, _, _ := res.Request("GET", nil, nil)
+	for i := 0; i < 2; i++ {
+		time.Sleep(200 * time.Millisecond)
+		if _, _, err := call(); err != nil {
+			t.Fatalf("Call() unexpected error %v", err)
+		}
+	}
+}
+
+/* Nominal case, url.Values are sent as a form whatever the marshaller */
+func TestResourceFormNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observed := req.Header.Get("Content-Type"); observed != "application/x-www-form-urlencoded" {
+			t.Errorf("Request() Content-Type = %v", observed)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "grant_type=password&username=julien" {
+			t.Errorf("Request() body = %v", string(body))
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := res.Request("POST", nil, netUrl.Values{"grant_type": []string{"password"}, "username": []string{"julien"}})
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	if _, _, err = call(); err != nil {
+		t.Errorf("call() unexpected error %v", err)
+	}
+}
+
+/* Error case, a prepared request whose body is streamed from a one-shot reader can only be sent once */
+func TestResourceCallTwiceOneShotBodyError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	var bodies []string
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		return &http.Response{
+			StatusCode: 204,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := res.Request("POST", nil, io.MultiReader(strings.NewReader("payload")))
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	if _, _, err := call(); err != nil {
+		t.Fatalf("call() unexpected error %v", err)
+	}
+	if _, _, err := call(); !errors.Is(err, misc.ErrBodyNotReplayable) {
+		t.Errorf("call() = %v, want %v", err, misc.ErrBodyNotReplayable)
+	}
+	if !reflect.DeepEqual(bodies, []string{"payload"}) {
+		t.Errorf("call() sent %q, want %q", bodies, []string{"payload"})
+	}
+}
+
+/* Nominal case, a struct with form tags is sent as a form when asked to, even though the marshaller is a JSON one */
+func TestResourceFormStructNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observed := req.Header.Get("Content-Type"); observed != "application/x-www-form-urlencoded" {
+			t.Errorf("Request() Content-Type = %v", observed)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "grant_type=password&username=julien" {
+			t.Errorf("Request() body = %v", string(body))
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	type login struct {
+		GrantType string `form:"grant_type"`
+		Username  string `form:"username"`
+		Password  string `form:"password,omitempty"`
+	}
+	call, _, err := res.Request("POST", nil, login{GrantType: "password", Username: "julien"}, WithEncoding("application/x-www-form-urlencoded"))
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	if _, _, err = call(); err != nil {
+		t.Errorf("call() unexpected error %v", err)
+	}
+}
+
+/* Nominal case, a multipart body is streamed, and rebuilt for every retry */
+func TestResourceMultipartNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		if err := req.ParseMultipartForm(1024); err != nil {
+			t.Fatalf("Request() unexpected error %v", err)
+		}
+		file, header, err := req.FormFile("avatar")
+		if err != nil {
+			t.Fatalf("Request() unexpected error %v", err)
+		}
+		content, _ := io.ReadAll(file)
+		if req.FormValue("name") != "julien" || header.Filename != "me.png" || string(content) != "PNG" {
+			t.Errorf("Request() attempt %v = %v, %v, %v", pass, req.FormValue("name"), header.Filename, string(content))
+		}
+		statusCode := 200
+		if pass == 1 {
+			statusCode = 503
+		}
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier())
+
+	body := serializers.NewMultipart().WithField("name", "julien").WithFile("avatar", "me.png", "image/png", strings.NewReader("PNG"))
+	call, _, err := res.Request("PUT", nil, body)
+	if err != nil {
+		t.Fatalf("Request() unexpected error %v", err)
+	}
+	if _, code, err := call(); err != nil || code != 200 || pass != 2 {
+		t.Errorf("call() = %v, %v after %v tries", code, err, pass)
+	}
+}
+
+/* Error case, a multipart body streamed from a one-shot reader cannot be retried, the first failure is returned */
+func TestResourceMultipartError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	pass := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		pass++
+		io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithRetrier(instantRetrier())
+
+	body := serializers.NewMultipart().WithFile("log", "log.txt", "text/plain", io.LimitReader(strings.NewReader("content"), 100))
+	call, _, _ := res.Request("PUT", nil, body)
+	if _, code, err := call(); err != nil || code != 503 || pass != 1 {
+		t.Errorf("call() = %v, %v after %v tries, want %v", code, err, pass, 503)
+	}
+}
+
+/* Nominal case, a response body within the maximum size */
+func TestWithMaxResponseSizeNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    200,
+			ContentLength: -1,
+			Body:          io.NopCloser(strings.NewReader(`{"id":"1234"}`)),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithMaxResponseSize(13)
+
+	call, _, _ := res.Request("GET", nil, nil)
+	if body, _, err := call(); err != nil || body.(map[string]interface{})["id"] != "1234" {
+		t.Errorf("call() = %v, %v", body, err)
+	}
+}
+
+/* Error case, a response body beyond the maximum size, whether its length is announced or not */
+func TestWithMaxResponseSizeError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	for _, contentLength := range []int64{-1, 14} {
+		mockClient := mocks.Client{}
+		mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    200,
+				ContentLength: contentLength,
+				Body:          io.NopCloser(strings.NewReader(`{"id":"12345"}`)),
+			}, nil
+		}
+		res := NewResource(url).WithClient(&mockClient).WithMaxResponseSize(13)
+
+		call, _, _ := res.Request("GET", nil, nil)
+		if _, code, err := call(); !errors.Is(err, ErrResponseTooLarge) || code != 200 {
+			t.Errorf("call() = %v, %v, want %v", code, err, ErrResponseTooLarge)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package retriers
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/* Compute how long to wait for before retrying */
+type Backoff interface {
+	//try is the number of the try that just failed, starting at 1, and previous the delay computed before it, 0 if none
+	Delay(try uint, previous time.Duration) time.Duration
+}
+
+// Shared source of randomness for the jittering strategies, safe for concurrent use
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+/* Pick a random duration within the [0,n[ interval, 0 if n isn't positive */
+func randomDuration(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	random.Lock()
+	defer random.Unlock()
+	return time.Duration(random.Int63n(int64(n)))
+}
+
+/* Limit a delay to a maximum, 0 meaning no limit, and handle overflows */
+func capDelay(delay time.Duration, max time.Duration) time.Duration {
+	if delay < 0 {
+		delay = time.Duration(math.MaxInt64)
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+/* Multiply a duration by a power of 2 without overflowing */
+func shift(base time.Duration, exponent uint) time.Duration {
+	if exponent >= 62 || base > time.Duration(math.MaxInt64>>exponent) {
+		return time.Duration(math.MaxInt64)
+	}
+	return base << exponent
+}
+
+/* Always wait for the same delay */
+type constantBackoff struct {
+	delay time.Duration
+}
+
+/* constantBackoff c'tor */
+func NewConstantBackoff(delay time.Duration) *constantBackoff {
+	return &constantBackoff{
+		delay: delay,
+	}
+}
+
+func (backoff *constantBackoff) Delay(try uint, previous time.Duration) time.Duration {
+	return backoff.delay
+}
+
+/* Wait for a delay growing linearly: base, 2*base, 3*base, ... */
+type linearBackoff struct {
+	base time.Duration
+	//Maximum delay, 0 means no limit
+	max time.Duration
+}
+
+/* linearBackoff c'tor */
+func NewLinearBackoff(base time.Duration) *linearBackoff {
+	return &linearBackoff{
+		base: base,
+	}
+}
+
+/* Set the maximum delay, 0 means no limit.
+Returns the updated backoff */
+func (backoff *linearBackoff) WithMax(max time.Duration) *linearBackoff {
+	backoff.max = max
+	return backoff
+}
+
+func (backoff *linearBackoff) Delay(try uint, previous time.Duration) time.Duration {
+	return capDelay(backoff.base*time.Duration(try), backoff.max)
+}
+
+/* Wait for a delay growing exponentially: base, 3*base, 7*base, ..., i.e. (2^try - 1)*base */
+type exponentialBackoff struct {
+	base time.Duration
+	//Maximum delay, 0 means no limit
+	max time.Duration
+	//Shift the delay by up to 25% of its increase, either way
+	jitter bool
+}
+
+/* exponentialBackoff c'tor, without jitter */
+func NewExponentialBackoff(base time.Duration) *exponentialBackoff {
+	return &exponentialBackoff{
+		base: base,
+	}
+}
+
+/* Set the maximum delay, 0 means no limit.
+Returns the updated backoff */
+func (backoff *exponentialBackoff) WithMax(max time.Duration) *exponentialBackoff {
+	backoff.max = max
+	return backoff
+}
+
+/* Do we allow the delay not to be exact, it can then be reduced or increased by 25% at most of its increase compared to the previous one.
+Returns the updated backoff */
+func (backoff *exponentialBackoff) WithJitter(jitter bool) *exponentialBackoff {
+	backoff.jitter = jitter
+	return backoff
+}
+
+func (backoff *exponentialBackoff) Delay(try uint, previous time.Duration) time.Duration {
+	delay := capDelay(shift(backoff.base, try)-backoff.base, backoff.max)
+	if backoff.jitter && try > 0 {
+		expectedPrevious := capDelay(shift(backoff.base, try-1)-backoff.base, backoff.max)
+		maxJitter := (delay - expectedPrevious) / 4
+		//Will pick a duration within the [-maxJitter,+maxJitter[ interval
+		delay += randomDuration(2*maxJitter) - maxJitter
+	}
+	return delay
+}
+
+/* Wait for a random delay within [0, min(max, base*2^try)[, see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/ */
+type fullJitterBackoff struct {
+	base time.Duration
+	//Maximum delay, 0 means no limit
+	max time.Duration
+}
+
+/* fullJitterBackoff c'tor */
+func NewFullJitterBackoff(base time.Duration) *fullJitterBackoff {
+	return &fullJitterBackoff{
+		base: base,
+	}
+}
+
+/* Set the maximum delay, 0 means no limit.
+Returns the updated backoff */
+func (backoff *fullJitterBackoff) WithMax(max time.Duration) *fullJitterBackoff {
+	backoff.max = max
+	return backoff
+}
+
+func (backoff *fullJitterBackoff) Delay(try uint, previous time.Duration) time.Duration {
+	return randomDuration(capDelay(shift(backoff.base, try), backoff.max))
+}
+
+/* Wait for a random delay within [base, 3*previous[, capped, see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/ */
+type decorrelatedJitterBackoff struct {
+	base time.Duration
+	//Maximum delay, 0 means no limit
+	max time.Duration
+}
+
+/* decorrelatedJitterBackoff c'tor */
+func NewDecorrelatedJitterBackoff(base time.Duration) *decorrelatedJitterBackoff {
+	return &decorrelatedJitterBackoff{
+		base: base,
+	}
+}
+
+/* Set the maximum delay, 0 means no limit.
+Returns the updated backoff */
+func (backoff *decorrelatedJitterBackoff) WithMax(max time.Duration) *decorrelatedJitterBackoff {
+	backoff.max = max
+	return backoff
+}
+
+func (backoff *decorrelatedJitterBackoff) Delay(try uint, previous time.Duration) time.Duration {
+	if previous < backoff.base {
+		previous = backoff.base
+	}
+	upper := capDelay(3*previous, 0)
+	return capDelay(backoff.base+randomDuration(upper-backoff.base), backoff.max)
+}
@@ -0,0 +1,80 @@
+package retriers
+
+import (
+	"testing"
+	"time"
+)
+
+/* Nominal case, constant delays */
+func TestConstantBackoffNominal(t *testing.T) {
+	backoff := NewConstantBackoff(250 * time.Millisecond)
+	for try := uint(1); try <= 3; try++ {
+		if observed := backoff.Delay(try, 0); observed != 250*time.Millisecond {
+			t.Errorf("Delay(%v) = %v, want %v", try, observed, 250*time.Millisecond)
+		}
+	}
+}
+
+/* Nominal case, linear delays, capped */
+func TestLinearBackoffNominal(t *testing.T) {
+	backoff := NewLinearBackoff(100 * time.Millisecond).WithMax(250 * time.Millisecond)
+	for try, expected := range map[uint]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 250 * time.Millisecond} {
+		if observed := backoff.Delay(try, 0); observed != expected {
+			t.Errorf("Delay(%v) = %v, want %v", try, observed, expected)
+		}
+	}
+}
+
+/* Nominal case, exponential delays, capped, without overflowing */
+func TestExponentialBackoffNominal(t *testing.T) {
+	backoff := NewExponentialBackoff(100 * time.Millisecond).WithMax(time.Minute)
+	for try, expected := range map[uint]time.Duration{1: 100 * time.Millisecond, 2: 300 * time.Millisecond, 3: 700 * time.Millisecond, 10: time.Minute, 100: time.Minute} {
+		if observed := backoff.Delay(try, 0); observed != expected {
+			t.Errorf("Delay(%v) = %v, want %v", try, observed, expected)
+		}
+	}
+}
+
+/* Nominal case, exponential delays with some jitter */
+func TestExponentialBackoffJitterNominal(t *testing.T) {
+	backoff := NewExponentialBackoff(100 * time.Millisecond).WithJitter(true)
+	for i := 0; i < 100; i++ {
+		//700ms expected, with an increase of 400ms compared to the previous one
+		if observed := backoff.Delay(3, 0); observed < 600*time.Millisecond || observed >= 800*time.Millisecond {
+			t.Fatalf("Delay() = %v, want within [%v,%v[", observed, 600*time.Millisecond, 800*time.Millisecond)
+		}
+	}
+}
+
+/* Nominal case, full jitter delays stay within bounds */
+func TestFullJitterBackoffNominal(t *testing.T) {
+	backoff := NewFullJitterBackoff(100 * time.Millisecond).WithMax(time.Second)
+	for i := 0; i < 100; i++ {
+		if observed := backoff.Delay(2, 0); observed < 0 || observed >= 400*time.Millisecond {
+			t.Fatalf("Delay() = %v, want within [0,%v[", observed, 400*time.Millisecond)
+		}
+		if observed := backoff.Delay(20, 0); observed < 0 || observed >= time.Second {
+			t.Fatalf("Delay() = %v, want within [0,%v[", observed, time.Second)
+		}
+	}
+}
+
+/* Nominal case, decorrelated jitter delays stay within bounds */
+func TestDecorrelatedJitterBackoffNominal(t *testing.T) {
+	backoff := NewDecorrelatedJitterBackoff(100 * time.Millisecond).WithMax(time.Second)
+	previous := time.Duration(0)
+	for try := uint(1); try <= 100; try++ {
+		observed := backoff.Delay(try, previous)
+		upper := 3 * previous
+		if upper < 300*time.Millisecond {
+			upper = 300 * time.Millisecond
+		}
+		if upper > time.Second {
+			upper = time.Second
+		}
+		if observed < 100*time.Millisecond || observed > upper {
+			t.Fatalf("Delay(%v, %v) = %v, want within [%v,%v]", try, previous, observed, 100*time.Millisecond, upper)
+		}
+		previous = observed
+	}
+}
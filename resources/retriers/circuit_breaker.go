@@ -0,0 +1,296 @@
+package retriers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+// default ratio of failed calls from which the circuit opens
+const defaultFailureRatio = 0.5
+
+// default minimum number of calls within the window before the failure ratio is considered
+const defaultMinRequests = 10
+
+// default duration of the window within which the calls are counted while the circuit is closed
+const defaultInterval = 60 * time.Second
+
+// default duration for which the circuit stays open before letting trial calls through
+const defaultOpenTimeout = 30 * time.Second
+
+// default number of successful trial calls required to close the circuit again
+const defaultHalfOpenTrials = 1
+
+// The circuit of a host is open, calls are not even tried
+var ErrCircuitOpen = errors.New("The circuit is open")
+
+/* The calls to a host are failing fast because its circuit is open */
+type CircuitOpenError struct {
+	//Host whose circuit is open
+	Host string
+	//When trial calls will be let through again
+	RetryAt time.Time
+}
+
+func (err *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v for %s until %s", ErrCircuitOpen, err.Host, err.RetryAt.Format(time.RFC3339))
+}
+
+/* Match ErrCircuitOpen */
+func (err *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+/* State of the circuit of a host */
+type State int
+
+const (
+	//Calls go through
+	StateClosed State = iota
+	//Calls fail fast
+	StateOpen
+	//A limited number of trial calls go through in order to probe the host
+	StateHalfOpen
+)
+
+func (state State) String() string {
+	switch state {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+/* Notify a change of the state of the circuit of a host */
+type StateChangeFunc func(host string, from State, to State)
+
+/* Circuit of a single host */
+type circuit struct {
+	state State
+	//Calls, and failed ones, counted within the current window while closed
+	requests uint
+	failures uint
+	//Start of the current window while closed
+	windowStart time.Time
+	//When the circuit last opened
+	openedAt time.Time
+	//Trial calls let through, and successful ones, while half-open
+	trials    uint
+	successes uint
+}
+
+/* Fail fast when a host keeps failing, and only try again from time to time, on top of another retrier */
+type circuitBreaker struct {
+	//Actual retrier for the calls that go through
+	retrier Retrier
+	//Ratio of failed calls from which the circuit opens
+	failureRatio float64
+	//Minimum number of calls within the window before the failure ratio is considered
+	minRequests uint
+	//Duration of the window within which the calls are counted while closed
+	interval time.Duration
+	//Duration for which the circuit stays open
+	openTimeout time.Duration
+	//Number of successful trial calls required to close the circuit again
+	halfOpenTrials uint
+	//Optional state change observer
+	onStateChange StateChangeFunc
+	//Time source
+	clock misc.Clock
+	//Circuits per host
+	circuits map[string]*circuit
+	mutex    sync.Mutex
+}
+
+/* Set the ratio of failed calls, within ]0,1], from which the circuit opens.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithFailureRatio(failureRatio float64) *circuitBreaker {
+	if failureRatio > 0 && failureRatio <= 1 {
+		breaker.failureRatio = failureRatio
+	}
+	return breaker
+}
+
+/* Set the minimum number of calls within the window before the failure ratio is considered. 1 is the minimum.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithMinRequests(minRequests uint) *circuitBreaker {
+	if minRequests > 0 {
+		breaker.minRequests = minRequests
+	}
+	return breaker
+}
+
+/* Set the duration of the window within which the calls are counted while the circuit is closed.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithInterval(interval time.Duration) *circuitBreaker {
+	if interval > 0 {
+		breaker.interval = interval
+	}
+	return breaker
+}
+
+/* Set the duration for which the circuit stays open before letting trial calls through.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithOpenTimeout(openTimeout time.Duration) *circuitBreaker {
+	if openTimeout > 0 {
+		breaker.openTimeout = openTimeout
+	}
+	return breaker
+}
+
+/* Set the number of successful trial calls required to close the circuit again. 1 is the minimum.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithHalfOpenTrials(halfOpenTrials uint) *circuitBreaker {
+	if halfOpenTrials > 0 {
+		breaker.halfOpenTrials = halfOpenTrials
+	}
+	return breaker
+}
+
+/* Be notified of every state change, e.g. for alerting.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithStateChange(onStateChange StateChangeFunc) *circuitBreaker {
+	breaker.onStateChange = onStateChange
+	return breaker
+}
+
+/* Use a specific time source.
+Returns the updated retrier */
+func (breaker *circuitBreaker) WithClock(clock misc.Clock) *circuitBreaker {
+	if clock != nil {
+		breaker.clock = clock
+	}
+	return breaker
+}
+
+/* circuitBreaker c'tor.
+retrier is the mandatory retrier actually making the calls that go through.
+By default the circuit of a host opens when at least half of at least 10 calls within a minute failed, then lets a trial call through after 30s */
+func NewCircuitBreaker(retrier Retrier) *circuitBreaker {
+	return &circuitBreaker{
+		retrier:        retrier,
+		failureRatio:   defaultFailureRatio,
+		minRequests:    defaultMinRequests,
+		interval:       defaultInterval,
+		openTimeout:    defaultOpenTimeout,
+		halfOpenTrials: defaultHalfOpenTrials,
+		clock:          misc.NewSystemClock(),
+		circuits:       map[string]*circuit{},
+	}
+}
+
+/* Current state of the circuit of a host */
+func (breaker *circuitBreaker) State(host string) State {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	if circuit, ok := breaker.circuits[host]; ok {
+		return circuit.state
+	}
+	return StateClosed
+}
+
+/* Try to make an HTTP request with the wrapped retrier unless the circuit of the host of the request is open.
+A call fails if it ends up with a transport error or a 5xx HTTP status code.
+Returns the response if successful, and the actual number of tries, 0 if the call failed fast with a CircuitOpenError */
+func (breaker *circuitBreaker) Try(client misc.HttpClient, request *http.Request) (*http.Response, uint, error) {
+	host := request.URL.Host
+	if err := breaker.admit(host); err != nil {
+		return nil, 0, err
+	}
+	response, tries, err := breaker.retrier.Try(client, request)
+	//A call given up by the caller says nothing about the health of the host
+	if err != nil && errors.Is(err, context.Canceled) {
+		breaker.release(host)
+	} else {
+		breaker.record(host, err != nil || (response != nil && response.StatusCode >= 500))
+	}
+	return response, tries, err
+}
+
+/* Let a call through, or not, depending on the state of the circuit of the host */
+func (breaker *circuitBreaker) admit(host string) error {
+	breaker.mutex.Lock()
+	now := breaker.clock.Now()
+	c, ok := breaker.circuits[host]
+	if !ok {
+		c = &circuit{windowStart: now}
+		breaker.circuits[host] = c
+	}
+	from := c.state
+	var err error
+	switch c.state {
+	case StateClosed:
+		if now.Sub(c.windowStart) >= breaker.interval {
+			c.requests, c.failures, c.windowStart = 0, 0, now
+		}
+	case StateOpen:
+		if now.Before(c.openedAt.Add(breaker.openTimeout)) {
+			err = &CircuitOpenError{Host: host, RetryAt: c.openedAt.Add(breaker.openTimeout)}
+			break
+		}
+		c.state, c.trials, c.successes = StateHalfOpen, 0, 0
+		fallthrough
+	case StateHalfOpen:
+		if c.trials < breaker.halfOpenTrials {
+			c.trials++
+		} else {
+			err = &CircuitOpenError{Host: host, RetryAt: now}
+		}
+	}
+	to := c.state
+	breaker.mutex.Unlock()
+	breaker.notify(host, from, to)
+	return err
+}
+
+/* Record the outcome of a call let through */
+func (breaker *circuitBreaker) record(host string, failed bool) {
+	breaker.mutex.Lock()
+	now := breaker.clock.Now()
+	c := breaker.circuits[host]
+	from := c.state
+	switch c.state {
+	case StateClosed:
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= breaker.minRequests && float64(c.failures)/float64(c.requests) >= breaker.failureRatio {
+			c.state, c.openedAt = StateOpen, now
+		}
+	case StateHalfOpen:
+		if failed {
+			c.state, c.openedAt = StateOpen, now
+		} else if c.successes++; c.successes >= breaker.halfOpenTrials {
+			c.state, c.requests, c.failures, c.windowStart = StateClosed, 0, 0, now
+		}
+	}
+	to := c.state
+	breaker.mutex.Unlock()
+	breaker.notify(host, from, to)
+}
+
+/* Forget a call let through whose outcome is irrelevant */
+func (breaker *circuitBreaker) release(host string) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	if c := breaker.circuits[host]; c.state == StateHalfOpen && c.trials > c.successes {
+		c.trials--
+	}
+}
+
+/* Call the state change observer, if any and if the state actually changed, outside of any lock */
+func (breaker *circuitBreaker) notify(host string, from State, to State) {
+	if from != to && breaker.onStateChange != nil {
+		breaker.onStateChange(host, from, to)
+	}
+}
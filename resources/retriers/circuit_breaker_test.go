@@ -0,0 +1,115 @@
+package retriers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, the circuit opens, fails fast, then closes again after a successful trial */
+func TestCircuitBreakerNominal(t *testing.T) {
+	expectedTransitions := []string{"closed>open", "open>half-open", "half-open>closed"}
+
+	mockClient := mocks.Client{}
+	serviceStatusCode := 500
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: serviceStatusCode,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	var transitions []string
+	breaker := NewCircuitBreaker(NewExponentialRetrier().WithMaxTries(1)).WithMinRequests(4).WithOpenTimeout(time.Minute).WithClock(&clock).WithStateChange(func(host string, from State, to State) {
+		if host != "nowhere" {
+			t.Errorf("WithStateChange() host = %v", host)
+		}
+		transitions = append(transitions, from.String()+">"+to.String())
+	})
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	for i := 0; i < 4; i++ {
+		breaker.Try(&mockClient, req)
+	}
+	if breaker.State("nowhere") != StateOpen {
+		t.Fatalf("State() = %v, want %v", breaker.State("nowhere"), StateOpen)
+	}
+
+	//Fail fast
+	_, n, err := breaker.Try(&mockClient, req)
+	if !errors.Is(err, ErrCircuitOpen) || n != 0 || calls != 4 {
+		t.Errorf("Try() = %v after %v tries, want %v after %v tries", err, n, ErrCircuitOpen, 0)
+	}
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Host != "nowhere" {
+		t.Errorf("Try() unexpected error %v", err)
+	}
+
+	//Other hosts are not impacted
+	if breaker.State("elsewhere") != StateClosed {
+		t.Errorf("State() = %v, want %v", breaker.State("elsewhere"), StateClosed)
+	}
+
+	//Probe once the circuit is half-open
+	clock.Current = clock.Current.Add(time.Minute)
+	serviceStatusCode = 200
+	response, _, err := breaker.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 200 {
+		t.Fatalf("Try() unexpected error %v", err)
+	}
+	if breaker.State("nowhere") != StateClosed {
+		t.Errorf("State() = %v, want %v", breaker.State("nowhere"), StateClosed)
+	}
+	if !reflect.DeepEqual(transitions, expectedTransitions) {
+		t.Errorf("WithStateChange() = %v, want %v", transitions, expectedTransitions)
+	}
+}
+
+/* Error case, a failed trial opens the circuit again */
+func TestCircuitBreakerFailedTrialError(t *testing.T) {
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	breaker := NewCircuitBreaker(NewExponentialRetrier().WithMaxTries(1)).WithMinRequests(1).WithClock(&clock)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	breaker.Try(&mockClient, req)
+	clock.Current = clock.Current.Add(defaultOpenTimeout)
+	if _, _, err := breaker.Try(&mockClient, req); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Try() unexpected error %v", err)
+	}
+	if breaker.State("nowhere") != StateOpen {
+		t.Errorf("State() = %v, want %v", breaker.State("nowhere"), StateOpen)
+	}
+}
+
+/* Nominal case, the calls are not counted outside of the window */
+func TestCircuitBreakerIntervalNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	clock := mocks.Clock{Current: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC)}
+	breaker := NewCircuitBreaker(NewExponentialRetrier().WithMaxTries(1)).WithMinRequests(2).WithInterval(time.Second).WithClock(&clock)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	breaker.Try(&mockClient, req)
+	clock.Current = clock.Current.Add(time.Second)
+	breaker.Try(&mockClient, req)
+	if breaker.State("nowhere") != StateClosed {
+		t.Errorf("State() = %v, want %v", breaker.State("nowhere"), StateClosed)
+	}
+}
@@ -3,9 +3,7 @@ package retriers
 import (
 	"context"
 	"errors"
-	"fmt"
-	"math"
-	"math/rand"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -21,6 +19,16 @@ func defaultRetryableCodes() []int { return []int{429, 500, 503, 504} }
 // Do we allow some random timing kew between each retry in order to avoid potential synchronized peaks of requests
 const defaultJittering = true
 
+// default maximum delay we accept to wait for when the server advises one
+const defaultMaxRetryAfter = 60 * time.Second
+
+// default base delay of the exponential back-off
+const defaultBackoffBase = 500 * time.Millisecond
+
+/* Observe every scheduled retry.
+try is the number of the try that just failed, delay is the computed time to wait before the next one, response and err are the outcome of the failed try */
+type Observer func(try uint, delay time.Duration, response *http.Response, err error)
+
 /* Retry sending the request if needed using an exponential back-off timing */
 type exponentialRetrier struct {
 	/* Number of times, at most, we can try to send the request.*/
@@ -29,6 +37,18 @@ type exponentialRetrier struct {
 	retryableCodes []int
 	/* Allow some jitter for each retry timing */
 	jitter bool
+	/* Maximum delay to wait for when the server advises one, 0 means no limit */
+	maxRetryAfter time.Duration
+	/* Optional observer of the scheduled retries */
+	observer Observer
+	/* Which requests can be retried at all */
+	policy Policy
+	/* Delay strategy between each try, an exponential back-off by default */
+	backoff Backoff
+	/* Time source */
+	clock misc.Clock
+	/* Optional diagnostics about the tries */
+	logger *slog.Logger
 }
 
 /* Set a maximum number of tries. 1 is the minimum.
@@ -49,42 +69,109 @@ func (retrier *exponentialRetrier) WithRetryableCodes(retryableCodes []int) *exp
 	return retrier
 }
 
-/* Do we allow the time between each retry not to be exact.
+/* Do we allow the time between each retry not to be exact, only meaningful for the default back-off strategy.
 Returns the updated resource */
 func (retrier *exponentialRetrier) WithJitter(jitter bool) *exponentialRetrier {
 	retrier.jitter = jitter
 	return retrier
 }
 
+/* Set the maximum delay to wait for when the server advises one with a Retry-After or a rate limit header, 0 means no limit.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithMaxRetryAfter(maxRetryAfter time.Duration) *exponentialRetrier {
+	retrier.maxRetryAfter = maxRetryAfter
+	return retrier
+}
+
+/* Observe every scheduled retry along with the delay before it.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithObserver(observer Observer) *exponentialRetrier {
+	retrier.observer = observer
+	return retrier
+}
+
+/* Set which requests can be retried at all.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithPolicy(policy Policy) *exponentialRetrier {
+	if policy != nil {
+		retrier.policy = policy
+	}
+	return retrier
+}
+
+/* Use a specific delay strategy between each try instead of the default exponential back-off.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithBackoff(backoff Backoff) *exponentialRetrier {
+	retrier.backoff = backoff
+	return retrier
+}
+
+/* Use a specific time source, e.g. in order not to actually wait between each try.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithClock(clock misc.Clock) *exponentialRetrier {
+	if clock != nil {
+		retrier.clock = clock
+	}
+	return retrier
+}
+
+/* Log the scheduled retries, and the final outcome when we give up, with the attempt, delay, status and error fields.
+Nothing is logged by default, or with a nil logger, and the credentials of the URL are redacted.
+Returns the updated resource */
+func (retrier *exponentialRetrier) WithLogger(logger *slog.Logger) *exponentialRetrier {
+	retrier.logger = logger
+	return retrier
+}
+
 /* exponentialRetrier c'tor.
-Will try at max. 3 times to send a request, with some jitter between each retry, if the HTTP response error is 429, 500, 503, or 504.*/
+Will try at max. 3 times to send an idempotent request, waiting (2^n - 1)*0.5s with some jitter before the nth retry, if the HTTP response error is 429, 500, 503, or 504.*/
 func NewExponentialRetrier() *exponentialRetrier {
 	return &exponentialRetrier{
 		maxTries:       defaultMaxTries,
 		retryableCodes: defaultRetryableCodes(),
 		jitter:         defaultJittering,
+		maxRetryAfter:  defaultMaxRetryAfter,
+		policy:         NewIdempotentPolicy(),
+		clock:          misc.NewSystemClock(),
 	}
 }
 
 /* Try to make an HTTP request with the given client for the specified prepared request.
 If unsuccessful, retry after some time if the HTTP error code allows it, or if the client timed out, and we don't go beyond the maximum allowed number of tries yet.
+The delay advised by the server, if any, takes precedence over the exponential back-off, and we give up if it goes beyond the deadline of the request.
+Every try is made with a clone of the request whose body is rebuilt, a body that cannot be rebuilt fails the retry with misc.ErrBodyNotReplayable.
 Returns the response if successful, and the actual number of tries */
 func (retrier *exponentialRetrier) Try(client misc.HttpClient, request *http.Request) (*http.Response, uint, error) {
 	var response *http.Response
 	var err error
-	var previousDelay int64 = 0
+	var previousDelay time.Duration
+	backoff := retrier.backoff
+	if backoff == nil {
+		backoff = NewExponentialBackoff(defaultBackoffBase).WithJitter(retrier.jitter)
+	}
 	var try uint
 	for try = 1; try <= retrier.maxTries; try++ {
 
-		//Timestamp the call, mandatory
-		request.Header.Set("Date", time.Now().UTC().Format(time.RFC1123))
+		//Do not even try if the caller already gave up
+		if ctxErr := request.Context().Err(); ctxErr != nil {
+			misc.Discard(response)
+			return nil, try - 1, ctxErr
+		}
+
+		//Every try needs its own copy of the request, and of its body
+		attempt, replayErr := misc.Replay(request, try == 1)
+		if replayErr != nil {
+			misc.Discard(response)
+			return nil, try - 1, replayErr
+		}
 
 		//Actual HTTP request
-		response, err = client.Do(request)
+		response, err = client.Do(attempt)
 		//Wait and analyse the result
 		canRetry := false
 		//One can only retry calling the service if the client timed out or if the service returned a compatible HTTP error code
-		if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		//but if the request context itself expired or was cancelled there's no point in trying again
+		if err != nil && errors.Is(err, context.DeadlineExceeded) && request.Context().Err() == nil {
 			canRetry = true
 		} else if response != nil {
 			for _, v := range retrier.retryableCodes {
@@ -94,26 +181,68 @@ func (retrier *exponentialRetrier) Try(client misc.HttpClient, request *http.Req
 				}
 			}
 		}
-		//If we need to retry then wait with an exponential back-off
-		if canRetry {
-			delay := int64(math.Floor((math.Pow(2, float64(try)) - 1) * 0.5))
-			//How much jitter should we apply?
-			//The delay can be be reduced or increased by 25% at most, compared to the expected
-			maxJitter := int64(float32(delay-previousDelay) * 0.25)
-			var jitter int64
-			if maxJitter > 0 {
-				rand.Seed(time.Now().UnixNano())
-				//Will pick a number within the [-maxJitter,+maxJitter[ interval
-				jitter = rand.Int63n(2*maxJitter) - maxJitter
-			} else {
-				jitter = 0
+		//If we need to retry then wait with an exponential back-off, as long as the request can be sent again safely
+		if canRetry && retrier.policy.CanRetry(attempt) {
+			//Do not wait for nothing if the last try is behind us
+			if try == retrier.maxTries {
+				retrier.log(request, slog.LevelWarn, "Giving up request, no try left", try, response, err)
+				break
+			}
+			//Do not wait for nothing either if the body cannot be sent again, the last response is all we can get
+			if !misc.Replayable(request) {
+				retrier.log(request, slog.LevelWarn, "Giving up request, body not replayable", try, response, err)
+				break
+			}
+			wait := backoff.Delay(try, previousDelay)
+			previousDelay = wait
+			//The server may know better than us when to try again
+			if response != nil {
+				if advisedDelay, ok := misc.RetryAfter(response.Header, retrier.clock.Now()); ok {
+					wait = advisedDelay
+					if retrier.maxRetryAfter > 0 && wait > retrier.maxRetryAfter {
+						wait = retrier.maxRetryAfter
+					}
+				}
+			}
+			//There's no point in waiting beyond the deadline of the request, give up right away with what we have
+			if deadline, ok := request.Context().Deadline(); ok && retrier.clock.Now().Add(wait).After(deadline) {
+				retrier.log(request, slog.LevelWarn, "Giving up request, deadline too close", try, response, err, slog.Duration("delay", wait))
+				break
+			}
+			if retrier.observer != nil {
+				retrier.observer(try, wait, response, err)
+			}
+			retrier.log(request, slog.LevelInfo, "Retrying request", try, response, err, slog.Duration("delay", wait))
+			//The response won't be used, free the connection before waiting
+			misc.Discard(response)
+			response = nil
+			if ctxErr := retrier.clock.Sleep(request.Context(), wait); ctxErr != nil {
+				return nil, try, ctxErr
 			}
-			previousDelay = delay
-			time.Sleep(time.Duration(delay+jitter) * time.Second)
 		} else {
 			break
 		}
 	}
-	fmt.Printf("err %v\n", err)
 	return response, try, err
 }
+
+/* Log a try with its structured fields, if a logger is set and enabled for the level.
+The credentials of the URL, if any, are redacted */
+func (retrier *exponentialRetrier) log(request *http.Request, level slog.Level, message string, try uint, response *http.Response, err error, extra ...slog.Attr) {
+	if retrier.logger == nil || !retrier.logger.Enabled(request.Context(), level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.String("url", request.URL.Redacted()),
+		slog.Uint64("attempt", uint64(try)),
+	}
+	attrs = append(attrs, extra...)
+	if response != nil {
+		attrs = append(attrs, slog.Int("status", response.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	retrier.logger.LogAttrs(request.Context(), level, message, attrs...)
+}
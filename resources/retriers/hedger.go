@@ -0,0 +1,243 @@
+package retriers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/misc"
+)
+
+// default maximum number of attempts running in parallel
+const defaultHedgedAttempts = 2
+
+// default delay before launching another attempt
+const defaultHedgingDelay = 100 * time.Millisecond
+
+// number of latencies kept in order to compute a percentile
+const hedgingWindow = 100
+
+// minimum number of latencies observed before relying on a percentile
+const minHedgingSamples = 10
+
+/* Outcome of a single hedged attempt */
+type hedgedResult struct {
+	//Rank of the attempt
+	index    int
+	response *http.Response
+	err      error
+	//Time taken by the attempt
+	latency time.Duration
+}
+
+/* Send the same request again, in parallel, if it takes too long to answer, in order to cut the tail latency.
+The first successful response is kept, the other attempts are cancelled */
+type hedger struct {
+	/* Number of attempts, at most, running in parallel */
+	maxAttempts uint
+	/* Delay before launching the next attempt */
+	delay time.Duration
+	/* Percentile of the observed latencies to use as a delay instead, 0 means disabled */
+	percentile float64
+	/* Which requests can be sent several times at all */
+	policy Policy
+	/* HTTP status codes that make an attempt fail rather than win, on top of the 5xx ones */
+	retryableCodes []int
+	/* Latest latencies of the successful attempts */
+	latencies []time.Duration
+	/* Index of the next latency to record */
+	next  int
+	mutex sync.Mutex
+}
+
+/* Set the maximum number of attempts running in parallel. 1 is the minimum, which disables the hedging.
+Returns the updated retrier */
+func (retrier *hedger) WithMaxAttempts(maxAttempts uint) *hedger {
+	if maxAttempts > 0 {
+		retrier.maxAttempts = maxAttempts
+	}
+	return retrier
+}
+
+/* Set the delay before launching the next attempt if none answered yet.
+Returns the updated retrier */
+func (retrier *hedger) WithDelay(delay time.Duration) *hedger {
+	retrier.delay = delay
+	return retrier
+}
+
+/* Wait for a percentile of the latencies observed so far, e.g. 0.95, before launching the next attempt.
+The fixed delay is used until enough latencies have been observed, 0 means disabled.
+Returns the updated retrier */
+func (retrier *hedger) WithPercentile(percentile float64) *hedger {
+	if percentile >= 0 && percentile <= 1 {
+		retrier.percentile = percentile
+	}
+	return retrier
+}
+
+/* Set which requests can be sent several times at all.
+Returns the updated retrier */
+func (retrier *hedger) WithPolicy(policy Policy) *hedger {
+	if policy != nil {
+		retrier.policy = policy
+	}
+	return retrier
+}
+
+/* Set the HTTP status codes that make an attempt fail rather than win, e.g. throttling ones, the 5xx ones always do.
+Returns the updated retrier */
+func (retrier *hedger) WithRetryableCodes(retryableCodes []int) *hedger {
+	if len(retryableCodes) > 0 {
+		retrier.retryableCodes = retryableCodes
+	}
+	return retrier
+}
+
+/* hedger c'tor.
+Will launch a second attempt at sending an idempotent request if the first one didn't answer within 100ms, or failed with a 429 or a 5xx */
+func NewHedger() *hedger {
+	return &hedger{
+		maxAttempts:    defaultHedgedAttempts,
+		delay:          defaultHedgingDelay,
+		policy:         NewIdempotentPolicy(),
+		retryableCodes: defaultRetryableCodes(),
+	}
+}
+
+/* Can the outcome of an attempt be returned right away, i.e. it is neither an error, nor a 5xx, nor a retryable status code */
+func (retrier *hedger) wins(result hedgedResult) bool {
+	if result.err != nil || result.response == nil || result.response.StatusCode >= 500 {
+		return false
+	}
+	for _, code := range retrier.retryableCodes {
+		if code == result.response.StatusCode {
+			return false
+		}
+	}
+	return true
+}
+
+/* Record the latency of a successful attempt */
+func (retrier *hedger) observe(latency time.Duration) {
+	retrier.mutex.Lock()
+	defer retrier.mutex.Unlock()
+	if len(retrier.latencies) < hedgingWindow {
+		retrier.latencies = append(retrier.latencies, latency)
+	} else {
+		retrier.latencies[retrier.next] = latency
+	}
+	retrier.next = (retrier.next + 1) % hedgingWindow
+}
+
+/* Compute the delay before launching the next attempt.
+Returns the percentile of the observed latencies if enough are known, the fixed delay otherwise */
+func (retrier *hedger) hedgingDelay() time.Duration {
+	if retrier.percentile <= 0 {
+		return retrier.delay
+	}
+	retrier.mutex.Lock()
+	latencies := append([]time.Duration(nil), retrier.latencies...)
+	retrier.mutex.Unlock()
+	if len(latencies) < minHedgingSamples {
+		return retrier.delay
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	index := int(retrier.percentile*float64(len(latencies))+0.5) - 1
+	if index < 0 {
+		index = 0
+	} else if index >= len(latencies) {
+		index = len(latencies) - 1
+	}
+	return latencies[index]
+}
+
+/* Try to make an HTTP request with the given client for the specified prepared request.
+If the request can be sent several times and no attempt answered after some delay, another one is launched in parallel, up to the maximum number of attempts.
+An attempt that fails, with an error, a 5xx, or a retryable status code such as 429, makes the next one launch right away.
+The first successful response is returned, the other attempts are cancelled and their responses discarded so that their connections go back to the pool.
+Every attempt is made with a clone of the request whose body is rebuilt, a request whose body cannot be rebuilt is sent only once.
+Returns the response if successful, or the last failure, and the actual number of attempts */
+func (retrier *hedger) Try(client misc.HttpClient, request *http.Request) (*http.Response, uint, error) {
+	maxAttempts := retrier.maxAttempts
+	if !retrier.policy.CanRetry(request) || !misc.Replayable(request) {
+		maxAttempts = 1
+	}
+
+	ctx := request.Context()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, 0, ctxErr
+	}
+
+	results := make(chan hedgedResult, maxAttempts)
+	var cancels []context.CancelFunc
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			start := time.Now()
+			//Every attempt needs its own copy of the request, and of its body
+			attempt, err := misc.Replay(request.WithContext(attemptCtx), index == 0)
+			var response *http.Response
+			if err == nil {
+				response, err = client.Do(attempt)
+			}
+			results <- hedgedResult{index: index, response: response, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(retrier.hedgingDelay())
+	defer timer.Stop()
+	pending := 1
+	failure := hedgedResult{index: -1}
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if retrier.wins(result) {
+				retrier.observe(result.latency)
+				//Cancel the losers, and free their connections once they come back
+				for index, cancel := range cancels {
+					if index != result.index {
+						cancel()
+					}
+				}
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						misc.Discard((<-results).response)
+					}
+				}(pending)
+				misc.Discard(failure.response)
+				misc.CancelOnClose(result.response, cancels[result.index])
+				return result.response, uint(len(cancels)), nil
+			}
+			//Keep the last failure only
+			if failure.index >= 0 {
+				misc.Discard(failure.response)
+				cancels[failure.index]()
+			}
+			failure = result
+			//No need to wait any longer for the next attempt
+			if uint(len(cancels)) < maxAttempts && ctx.Err() == nil {
+				launch()
+				pending++
+			}
+		case <-timer.C:
+			if uint(len(cancels)) < maxAttempts && ctx.Err() == nil {
+				launch()
+				pending++
+				timer.Reset(retrier.hedgingDelay())
+			}
+		}
+	}
+	if failure.response != nil && failure.response.Body != nil {
+		misc.CancelOnClose(failure.response, cancels[failure.index])
+	} else {
+		cancels[failure.index]()
+	}
+	return failure.response, uint(len(cancels)), failure.err
+}
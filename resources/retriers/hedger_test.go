@@ -0,0 +1,249 @@
+package retriers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Body tracking whether it was closed */
+type closingBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (body *closingBody) Close() error {
+	close(body.closed)
+	return nil
+}
+
+/* Nominal case, the second attempt answers first, the first one is cancelled */
+func TestHedgerNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	var mutex sync.Mutex
+	calls := 0
+	cancelled := make(chan struct{})
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		calls++
+		call := calls
+		mutex.Unlock()
+		if call == 1 {
+			<-req.Context().Done()
+			close(cancelled)
+			return nil, req.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(10 * time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 200 || n != 2 {
+		t.Fatalf("Try() = %v, %v after %v attempts", response, err, n)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("Try() did not cancel the slowest attempt")
+	}
+	response.Body.Close()
+}
+
+/* Nominal case, the first attempt answers in time, no other is launched */
+func TestHedgerFastNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Second)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 200 || n != 1 || calls != 1 {
+		t.Errorf("Try() = %v, %v after %v attempts", response, err, n)
+	}
+}
+
+/* Nominal case, a non-idempotent request is never sent twice */
+func TestHedgerNotIdempotentNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		calls++
+		time.Sleep(30 * time.Millisecond)
+		return &http.Response{
+			StatusCode: 201,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Millisecond).WithMaxAttempts(3)
+
+	req, _ := http.NewRequest("POST", "http://nowhere", strings.NewReader("{}"))
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 201 || n != 1 || calls != 1 {
+		t.Errorf("Try() = %v, %v after %v attempts", response, err, n)
+	}
+}
+
+/* Nominal case, a request whose body cannot be sent again is not hedged, its actual response is returned */
+func TestHedgerNotReplayableNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Millisecond).WithMaxAttempts(3)
+
+	req, _ := http.NewRequest("PUT", "http://nowhere", io.MultiReader(strings.NewReader("stream")))
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response == nil || response.StatusCode != 503 || n != 1 || calls != 1 {
+		t.Errorf("Try() = %v, %v after %v attempts, want the 503 response", response, err, n)
+	}
+}
+
+/* Nominal case, the response of a loser is drained and closed once it comes back */
+func TestHedgerDrainNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	var mutex sync.Mutex
+	calls := 0
+	loserBody := &closingBody{Reader: strings.NewReader("{}"), closed: make(chan struct{})}
+	release := make(chan struct{})
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		calls++
+		call := calls
+		mutex.Unlock()
+		if call == 1 {
+			//Ignore the cancellation
+			<-release
+			return &http.Response{StatusCode: 200, Body: loserBody}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	_, n, err := hedger.Try(&mockClient, req)
+	if err != nil || n != 2 {
+		t.Fatalf("Try() = %v after %v attempts", err, n)
+	}
+	close(release)
+	select {
+	case <-loserBody.closed:
+	case <-time.After(time.Second):
+		t.Errorf("Try() did not discard the response of the slowest attempt")
+	}
+}
+
+/* Error case, every attempt fails, the last failure is returned */
+func TestHedgerError(t *testing.T) {
+	mockClient := mocks.Client{}
+	var mutex sync.Mutex
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Second).WithMaxAttempts(3)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 503 || n != 3 || calls != 3 {
+		t.Errorf("Try() = %v, %v after %v attempts", response, err, n)
+	}
+}
+
+/* Nominal case, a throttled first attempt does not win, the next one is launched right away */
+func TestHedgerThrottledNominal(t *testing.T) {
+	mockClient := mocks.Client{}
+	var mutex sync.Mutex
+	calls := 0
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		status := 200
+		if calls == 1 {
+			status = 429
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader("{}")),
+		}, nil
+	}
+	hedger := NewHedger().WithDelay(time.Second)
+
+	req, _ := http.NewRequest("GET", "http://nowhere", nil)
+	start := time.Now()
+	response, n, err := hedger.Try(&mockClient, req)
+	if err != nil || response.StatusCode != 200 || n != 2 {
+		t.Fatalf("Try() = %v, %v after %v attempts", response, err, n)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Try() took %v, want the second attempt right away", elapsed)
+	}
+}
+
+/* Error case, the caller gives up */
+func TestHedgerCancelError(t *testing.T) {
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	hedger := NewHedger().WithDelay(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://nowhere", nil)
+	_, n, err := hedger.Try(&mockClient, req)
+	if !errors.Is(err, context.DeadlineExceeded) || n != 2 {
+		t.Errorf("Try() = %v after %v attempts, want %v", err, n, context.DeadlineExceeded)
+	}
+}
+
+/* Nominal case, the delay follows the observed latencies */
+func TestHedgerPercentileNominal(t *testing.T) {
+	hedger := NewHedger().WithDelay(time.Second).WithPercentile(0.9)
+	for i := 1; i <= 5; i++ {
+		hedger.observe(time.Duration(i) * time.Millisecond)
+	}
+	if delay := hedger.hedgingDelay(); delay != time.Second {
+		t.Errorf("hedgingDelay() = %v, want %v", delay, time.Second)
+	}
+	for i := 6; i <= 2*hedgingWindow; i++ {
+		hedger.observe(time.Duration(i) * time.Millisecond)
+	}
+	//Only the latest latencies are kept, 101ms to 200ms
+	if delay := hedger.hedgingDelay(); delay != 190*time.Millisecond {
+		t.Errorf("hedgingDelay() = %v, want %v", delay, 190*time.Millisecond)
+	}
+}
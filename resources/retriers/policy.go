@@ -0,0 +1,46 @@
+package retriers
+
+import (
+	"net/http"
+	"strings"
+)
+
+/* Decide whether a request can safely be sent more than once */
+type Policy interface {
+	CanRetry(request *http.Request) bool
+}
+
+/* Only retry the requests whose effect is the same whether they are sent once or several times */
+type idempotentPolicy struct {
+	//Also retry the non-idempotent requests carrying an Idempotency-Key header
+	withIdempotencyKey bool
+}
+
+/* Also retry the POST and PATCH requests carrying an Idempotency-Key header, as the server can then detect the duplicates.
+Returns the updated policy */
+func (policy *idempotentPolicy) WithIdempotencyKey(withIdempotencyKey bool) *idempotentPolicy {
+	policy.withIdempotencyKey = withIdempotencyKey
+	return policy
+}
+
+/* idempotentPolicy c'tor.
+By default only GET, HEAD, PUT, DELETE, and OPTIONS requests can be retried */
+func NewIdempotentPolicy() *idempotentPolicy {
+	return &idempotentPolicy{}
+}
+
+func (policy *idempotentPolicy) CanRetry(request *http.Request) bool {
+	if IsIdempotent(request.Method) {
+		return true
+	}
+	return policy.withIdempotencyKey && len(request.Header.Get("Idempotency-Key")) > 0
+}
+
+/* Is the case-insensitive HTTP verb idempotent, see RFC 7231 section 4.2.2 */
+func IsIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package retriers
+
+import (
+	"net/http"
+	"testing"
+)
+
+/* Nominal case, only the idempotent verbs are retried by default */
+func TestIdempotentPolicyNominal(t *testing.T) {
+	policy := NewIdempotentPolicy()
+	for method, expected := range map[string]bool{
+		"GET":     true,
+		"head":    true,
+		"PUT":     true,
+		"DELETE":  true,
+		"OPTIONS": true,
+		"POST":    false,
+		"PATCH":   false,
+	} {
+		req, _ := http.NewRequest(method, "http://nowhere", nil)
+		req.Header.Set("Idempotency-Key", "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+		if observed := policy.CanRetry(req); observed != expected {
+			t.Errorf("CanRetry(%v) = %v, want %v", method, observed, expected)
+		}
+	}
+}
+
+/* Nominal case, non-idempotent verbs are retried if they carry an idempotency key */
+func TestIdempotentPolicyWithIdempotencyKeyNominal(t *testing.T) {
+	policy := NewIdempotentPolicy().WithIdempotencyKey(true)
+	req, _ := http.NewRequest("POST", "http://nowhere", nil)
+	if policy.CanRetry(req) {
+		t.Errorf("CanRetry() = %v, want %v", true, false)
+	}
+	req.Header.Set("Idempotency-Key", "ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	if !policy.CanRetry(req) {
+		t.Errorf("CanRetry() = %v, want %v", false, true)
+	}
+}
@@ -0,0 +1,163 @@
+package serializers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The document is encoded with a charset we cannot convert
+var ErrUnsupportedCharset = errors.New("Unsupported charset")
+
+// Unicode code points of the windows-1252 bytes between 0x80 and 0x9F, the undefined ones being replaced
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+/* Convert single-byte encoded text into UTF-8 on the fly */
+type singleByteReader struct {
+	input *bufio.Reader
+	//Unicode code point of every byte
+	decode func(b byte) rune
+	//Encoded runes not read yet
+	pending []byte
+}
+
+func (reader *singleByteReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(reader.pending) > 0 {
+			copied := copy(p[n:], reader.pending)
+			reader.pending = reader.pending[copied:]
+			n += copied
+			continue
+		}
+		b, err := reader.input.ReadByte()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		reader.pending = utf8.AppendRune(reader.pending[:0], reader.decode(b))
+	}
+	return n, nil
+}
+
+/* Convert the input into UTF-8 according to its charset, e.g. as declared by an XML document.
+ISO-8859-1, windows-1252, and US-ASCII are supported.
+Returns the converted input, or ErrUnsupportedCharset */
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	decode, err := charsetDecoder(charset)
+	if err != nil {
+		return nil, err
+	} else if decode == nil {
+		return input, nil
+	}
+	return &singleByteReader{input: bufio.NewReader(input), decode: decode}, nil
+}
+
+/* Unicode code point of every byte of a single-byte charset.
+Returns nil for UTF-8, or ErrUnsupportedCharset */
+func charsetDecoder(charset string) (func(b byte) rune, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return nil, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return func(b byte) rune { return rune(b) }, nil
+	case "windows-1252", "cp1252":
+		return func(b byte) rune {
+			if b >= 0x80 && b <= 0x9F {
+				return windows1252[b-0x80]
+			}
+			return rune(b)
+		}, nil
+	case "us-ascii", "ascii":
+		return func(b byte) rune {
+			if b > 0x7F {
+				return utf8.RuneError
+			}
+			return rune(b)
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedCharset, charset)
+}
+
+/* Marshaller decoding documents that may declare their own charset, which must give way to the one of the media type */
+type charsetOverrider interface {
+	withCharset(charset string) Marshaller
+}
+
+/* Adapt the marshaller to the charset parameter of the content type, if any and if not UTF-8, the documents being converted into UTF-8 before being decoded.
+Returns the adapted marshaller, or ErrUnsupportedCharset */
+func withCharset(marshaller Marshaller, contentType string) (Marshaller, error) {
+	mediaType, err := ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	charset := mediaType.Params["charset"]
+	if len(charset) == 0 {
+		return marshaller, nil
+	}
+	if decode, err := charsetDecoder(charset); err != nil {
+		return nil, err
+	} else if decode == nil {
+		return marshaller, nil
+	}
+
+	if overrider, ok := marshaller.(charsetOverrider); ok {
+		return overrider.withCharset(charset), nil
+	}
+	converter := charsetMarshaller{Marshaller: marshaller, charset: charset}
+	if _, ok := marshaller.(StreamMarshaller); ok {
+		return &charsetStreamMarshaller{converter}, nil
+	}
+	return &converter, nil
+}
+
+/* Convert the documents into UTF-8 before handing them over to the actual marshaller */
+type charsetMarshaller struct {
+	Marshaller
+	//Supported charset of the documents
+	charset string
+}
+
+func (marshaller *charsetMarshaller) Deserialize(input []byte) (interface{}, error) {
+	converted, err := marshaller.convert(input)
+	if err != nil {
+		return nil, err
+	}
+	return marshaller.Marshaller.Deserialize(converted)
+}
+
+func (marshaller *charsetMarshaller) DeserializeInto(input []byte, output interface{}) error {
+	converted, err := marshaller.convert(input)
+	if err != nil {
+		return err
+	}
+	return DeserializeInto(marshaller.Marshaller, converted, output)
+}
+
+func (marshaller *charsetMarshaller) convert(input []byte) ([]byte, error) {
+	reader, err := charsetReader(marshaller.charset, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+/* Convert the streams into UTF-8 before handing them over to the actual marshaller */
+type charsetStreamMarshaller struct {
+	charsetMarshaller
+}
+
+func (marshaller *charsetStreamMarshaller) NewDecoder(input io.Reader) StreamDecoder {
+	//The charset was checked beforehand
+	reader, _ := charsetReader(marshaller.charset, input)
+	return marshaller.Marshaller.(StreamMarshaller).NewDecoder(reader)
+}
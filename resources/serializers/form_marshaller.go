@@ -0,0 +1,208 @@
+package serializers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The value cannot be (de)serialized as a form
+var ErrUnsupportedForm = errors.New("Unsupported form value")
+
+/* (Un)marshaller for application/x-www-form-urlencoded forms.
+The forms are url.Values, maps of strings or string slices, or structs whose fields are named with form:"name,omitempty" tags */
+type formMarshaller struct{}
+
+/* Marshaller c'tor */
+func NewFormMarshaller() *formMarshaller {
+	return &formMarshaller{}
+}
+
+/* Encode url.Values, map[string]string, map[string][]string, or a struct, or a pointer to them */
+func (marshaller *formMarshaller) Serialize(input interface{}) ([]byte, error) {
+	values, err := EncodeForm(input)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (marshaller *formMarshaller) SerializationCompatibleMimetype() string {
+	return "application/x-www-form-urlencoded"
+}
+
+/* Decode the form into url.Values */
+func (marshaller *formMarshaller) Deserialize(input []byte) (interface{}, error) {
+	return url.ParseQuery(string(input))
+}
+
+/* Decode the form into a pointer to url.Values, map[string]string, map[string][]string, or a struct */
+func (marshaller *formMarshaller) DeserializeInto(input []byte, output interface{}) error {
+	values, err := url.ParseQuery(string(input))
+	if err != nil {
+		return err
+	}
+	return DecodeForm(values, output)
+}
+
+func (marshaller *formMarshaller) DeserializationCompatibleMimetypes() []string {
+	return []string{
+		"application/x-www-form-urlencoded",
+	}
+}
+
+/* Name of the form field of a struct field, and whether it is omitted when empty.
+Returns an empty name if the field must be skipped */
+func formField(field reflect.StructField) (string, bool) {
+	if len(field.PkgPath) > 0 {
+		return "", false
+	}
+	tag := field.Tag.Get("form")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if len(name) == 0 {
+		name = field.Name
+	}
+	return name, options == "omitempty"
+}
+
+/* Build a form out of url.Values, map[string]string, map[string][]string, or a struct with form tags, or a pointer to them.
+Slices and arrays are encoded as repeated fields.
+Returns the form values */
+func EncodeForm(input interface{}) (url.Values, error) {
+	switch typedInput := input.(type) {
+	case url.Values:
+		return typedInput, nil
+	case *url.Values:
+		return *typedInput, nil
+	case map[string][]string:
+		return url.Values(typedInput), nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range typedInput {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(input))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedForm, input)
+	}
+	values := url.Values{}
+	for i := 0; i < value.NumField(); i++ {
+		name, omitEmpty := formField(value.Type().Field(i))
+		if len(name) == 0 {
+			continue
+		}
+		field := value.Field(i)
+		if omitEmpty && field.IsZero() {
+			continue
+		}
+		field = reflect.Indirect(field)
+		if !field.IsValid() {
+			continue
+		}
+		if (field.Kind() == reflect.Slice || field.Kind() == reflect.Array) && field.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < field.Len(); j++ {
+				values.Add(name, fmt.Sprint(field.Index(j).Interface()))
+			}
+		} else {
+			values.Add(name, fmt.Sprint(field.Interface()))
+		}
+	}
+	return values, nil
+}
+
+/* Fill a pointer to url.Values, map[string]string, map[string][]string, or a struct with form tags, with the form values.
+The struct fields can be strings, booleans, numbers, pointers to them, or slices of them for repeated fields */
+func DecodeForm(values url.Values, output interface{}) error {
+	switch typedOutput := output.(type) {
+	case *url.Values:
+		*typedOutput = values
+		return nil
+	case *map[string][]string:
+		*typedOutput = values
+		return nil
+	case *map[string]string:
+		*typedOutput = map[string]string{}
+		for key := range values {
+			(*typedOutput)[key] = values.Get(key)
+		}
+		return nil
+	}
+
+	pointer := reflect.ValueOf(output)
+	if pointer.Kind() != reflect.Ptr || pointer.IsNil() || pointer.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrUnsupportedForm, output)
+	}
+	value := pointer.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, _ := formField(value.Type().Field(i))
+		fieldValues, ok := values[name]
+		if len(name) == 0 || !ok {
+			continue
+		}
+		field := value.Field(i)
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(field.Type(), len(fieldValues), len(fieldValues))
+			for j, fieldValue := range fieldValues {
+				if err := setFormValue(slice.Index(j), fieldValue); err != nil {
+					return fmt.Errorf("%w: %s", err, name)
+				}
+			}
+			field.Set(slice)
+		} else if err := setFormValue(field, fieldValues[0]); err != nil {
+			return fmt.Errorf("%w: %s", err, name)
+		}
+	}
+	return nil
+}
+
+/* Parse a single form value into a field according to its kind */
+func setFormValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		element := reflect.New(field.Type().Elem())
+		if err := setFormValue(element.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(element)
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedForm, field.Type())
+	}
+	return nil
+}
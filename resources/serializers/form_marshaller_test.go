@@ -0,0 +1,84 @@
+package serializers
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type signUp struct {
+	Login    string   `form:"login"`
+	Age      int      `form:"age,omitempty"`
+	Admin    bool     `form:"admin"`
+	Score    *float64 `form:"score,omitempty"`
+	Tags     []string `form:"tag"`
+	Password string   `form:"-"`
+	Comment  string
+	internal string
+}
+
+/* Nominal case, marshalling and unmarshalling a struct form */
+func TestFormMarshallerNominalMarshallingUnmarshalling(t *testing.T) {
+	score := 9.5
+	data := signUp{
+		Login:    "julien",
+		Admin:    true,
+		Score:    &score,
+		Tags:     []string{"a", "b&c"},
+		Password: "secret",
+		Comment:  "hi there",
+	}
+	marshaller := NewFormMarshaller()
+	encoded, err := marshaller.Serialize(&data)
+	if err != nil {
+		t.Fatalf("Serialize() unexpected error %v", err)
+	}
+	expected := "Comment=hi+there&admin=true&login=julien&score=9.5&tag=a&tag=b%26c"
+	if string(encoded) != expected {
+		t.Errorf("Serialize() = %v, want %v", string(encoded), expected)
+	}
+
+	var decoded signUp
+	if err = marshaller.DeserializeInto(append(encoded, []byte("&age=42")...), &decoded); err != nil {
+		t.Fatalf("DeserializeInto() unexpected error %v", err)
+	}
+	data.Password = ""
+	data.Age = 42
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("DeserializeInto() = %+v, want %+v", decoded, data)
+	}
+}
+
+/* Nominal case, marshalling and unmarshalling maps */
+func TestFormMarshallerNominalMaps(t *testing.T) {
+	marshaller := NewFormMarshaller()
+	encoded, err := marshaller.Serialize(map[string]string{"b": "2", "a": "1"})
+	if err != nil || string(encoded) != "a=1&b=2" {
+		t.Errorf("Serialize() = %v, %v", string(encoded), err)
+	}
+	decoded, err := marshaller.Deserialize([]byte("a=1&a=2&b=3"))
+	expected := url.Values{"a": []string{"1", "2"}, "b": []string{"3"}}
+	if err != nil || !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Deserialize() = %v, %v", decoded, err)
+	}
+	var flat map[string]string
+	if err = marshaller.DeserializeInto([]byte("a=1&a=2&b=3"), &flat); err != nil || !reflect.DeepEqual(flat, map[string]string{"a": "1", "b": "3"}) {
+		t.Errorf("DeserializeInto() = %v, %v", flat, err)
+	}
+}
+
+/* Error case, values that are not forms */
+func TestFormMarshallerError(t *testing.T) {
+	marshaller := NewFormMarshaller()
+	if _, err := marshaller.Serialize(42); !errors.Is(err, ErrUnsupportedForm) {
+		t.Errorf("Serialize() = %v, want %v", err, ErrUnsupportedForm)
+	}
+	var output signUp
+	if err := marshaller.DeserializeInto([]byte("age=old"), &output); err == nil {
+		t.Errorf("DeserializeInto() unexpected success")
+	}
+	if err := marshaller.DeserializeInto([]byte("age=42"), output); !errors.Is(err, ErrUnsupportedForm) {
+		t.Errorf("DeserializeInto() = %v, want %v", err, ErrUnsupportedForm)
+	}
+}
@@ -2,6 +2,7 @@ package serializers
 
 import (
 	"encoding/json"
+	"io"
 )
 
 /* (Un)marshaller for JSON */
@@ -26,9 +27,20 @@ func (marshaller *jsonMarshaller) Deserialize(input []byte) (interface{}, error)
 	return output, err
 }
 
+func (marshaller *jsonMarshaller) DeserializeInto(input []byte, output interface{}) error {
+	return json.Unmarshal(input, output)
+}
+
 func (marshaller *jsonMarshaller) DeserializationCompatibleMimetypes() []string {
 	return []string{
 		"application/vnd.api+json",
 		"application/json",
 	}
 }
+
+func (marshaller *jsonMarshaller) NewDecoder(input io.Reader) StreamDecoder {
+	return json.NewDecoder(input)
+}
+
+// Make sure the marshaller can decode streams
+var _ StreamMarshaller = (*jsonMarshaller)(nil)
@@ -1,7 +1,9 @@
 package serializers
 
 import (
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/mapstructure"
@@ -121,3 +123,45 @@ func TestJsonMarshallerNominalCompatibleDeserializationMimeType(t *testing.T) {
 		t.Errorf("DeserializationCompatibleMimetypes() = %v, want %v", serializer.DeserializationCompatibleMimetypes(), probe2)
 	}
 }
+
+/* Nominal case, unmarshalling JSON data straight into a struct */
+func TestJsonMarshallerNominalUnmarshallingInto(t *testing.T) {
+	type Movement struct {
+		Label string  `json:"label"`
+		Price float32 `json:"price"`
+	}
+	expected := Movement{
+		Label: "Supermarket",
+		Price: 10.52,
+	}
+
+	serializer := NewJsonMarshaller()
+	var observed Movement
+	err := serializer.DeserializeInto([]byte(`{"label":"Supermarket","price":10.52}`), &observed)
+	if err != nil {
+		t.Fatalf("DeserializeInto() unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("DeserializeInto() = %v, want %v", observed, expected)
+	}
+}
+
+/* Nominal case, decoding a stream of JSON values */
+func TestJsonMarshallerNominalStreamDecoding(t *testing.T) {
+	decoder := NewJsonMarshaller().NewDecoder(strings.NewReader(`{"label":"a"} {"label":"b"}`))
+	var labels []string
+	for {
+		var movement struct {
+			Label string `json:"label"`
+		}
+		if err := decoder.Decode(&movement); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode() unexpected error %v", err)
+		}
+		labels = append(labels, movement.Label)
+	}
+	if !reflect.DeepEqual(labels, []string{"a", "b"}) {
+		t.Errorf("Decode() = %v", labels)
+	}
+}
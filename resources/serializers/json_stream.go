@@ -0,0 +1,132 @@
+package serializers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/* A single element of a stream, or the error met while decoding it */
+type Item[T any] struct {
+	//Rank of the element in the stream, from 0
+	Index int
+	//Decoded element, the zero value if Err is set
+	Value T
+	//Decoding error of this element only, unless it is the last item of the stream
+	Err error
+}
+
+/*
+Decode a stream of JSON elements one by one, either a JSON array or newline-delimited JSON (NDJSON), detected from its first character.
+The elements are sent over the returned channel as soon as they are decoded, which is closed at the end of the stream.
+The channel is unbuffered so that the input is only read as fast as the elements are received.
+An element that cannot be decoded into T is sent with its error without interrupting the stream, unlike a read error, a malformed JSON array, or the cancellation of ctx.
+The input is not closed, it should be bound to ctx too, e.g. a Stream, for its reading to be interrupted as soon as ctx is cancelled
+*/
+func DecodeJSONStream[T any](ctx context.Context, input io.Reader) <-chan Item[T] {
+	items := make(chan Item[T])
+	go func() {
+		defer close(items)
+		reader := bufio.NewReader(input)
+		first, err := firstCharacter(reader)
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			sendItem(ctx, items, Item[T]{Err: err})
+			return
+		}
+		if first == '[' {
+			decodeJSONArray(ctx, reader, items)
+		} else {
+			decodeNDJSON(ctx, reader, items)
+		}
+	}()
+	return items
+}
+
+/* Send an item unless the context is cancelled first.
+Returns false if the item cannot be sent */
+func sendItem[T any](ctx context.Context, items chan<- Item[T], item Item[T]) bool {
+	select {
+	case items <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+/* Decode a raw element into a new item */
+func newItem[T any](index int, raw []byte) Item[T] {
+	item := Item[T]{Index: index}
+	if err := json.Unmarshal(raw, &item.Value); err != nil {
+		var zero T
+		item.Value = zero
+		item.Err = fmt.Errorf("element %d: %w", index, err)
+	}
+	return item
+}
+
+/* Peek the first non-whitespace character of the input */
+func firstCharacter(reader *bufio.Reader) (byte, error) {
+	for {
+		character, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if character != ' ' && character != '\t' && character != '\r' && character != '\n' {
+			return character, reader.UnreadByte()
+		}
+	}
+}
+
+/* Decode the elements of a JSON array, a malformed array ends the stream */
+func decodeJSONArray[T any](ctx context.Context, reader io.Reader, items chan<- Item[T]) {
+	decoder := json.NewDecoder(reader)
+	//Opening bracket
+	if _, err := decoder.Token(); err != nil {
+		sendItem(ctx, items, Item[T]{Err: err})
+		return
+	}
+	for index := 0; decoder.More(); index++ {
+		if ctx.Err() != nil {
+			return
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			sendItem(ctx, items, Item[T]{Index: index, Err: err})
+			return
+		}
+		if !sendItem(ctx, items, newItem[T](index, raw)) {
+			return
+		}
+	}
+	//Closing bracket
+	if _, err := decoder.Token(); err != nil {
+		sendItem(ctx, items, Item[T]{Err: err})
+	}
+}
+
+/* Decode the lines of newline-delimited JSON, a malformed line being reported on its own */
+func decodeNDJSON[T any](ctx context.Context, reader *bufio.Reader, items chan<- Item[T]) {
+	for index := 0; ; {
+		if ctx.Err() != nil {
+			return
+		}
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if !sendItem(ctx, items, newItem[T](index, line)) {
+				return
+			}
+			index++
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			sendItem(ctx, items, Item[T]{Index: index, Err: err})
+			return
+		}
+	}
+}
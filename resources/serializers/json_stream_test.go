@@ -0,0 +1,130 @@
+package serializers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamedMovement struct {
+	Label string  `json:"label"`
+	Price float32 `json:"price"`
+}
+
+/* Collect every item of a stream */
+func collect[T any](items <-chan Item[T]) ([]T, []error) {
+	var values []T
+	var errs []error
+	for item := range items {
+		if item.Err != nil {
+			errs = append(errs, item.Err)
+		} else {
+			values = append(values, item.Value)
+		}
+	}
+	return values, errs
+}
+
+/* Nominal case, decoding a JSON array element by element */
+func TestDecodeJSONStreamNominalArray(t *testing.T) {
+	input := ` [{"label":"a","price":1}, {"label":"b","price":"free"}, {"label":"c","price":3}]`
+	values, errs := collect(DecodeJSONStream[streamedMovement](context.Background(), strings.NewReader(input)))
+	expected := []streamedMovement{{Label: "a", Price: 1}, {Label: "c", Price: 3}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("DecodeJSONStream() = %v, want %v", values, expected)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "element 1") {
+		t.Errorf("DecodeJSONStream() errors = %v", errs)
+	}
+}
+
+/* Nominal case, decoding newline-delimited JSON, a malformed line does not stop the stream */
+func TestDecodeJSONStreamNominalNDJSON(t *testing.T) {
+	input := "{\"label\":\"a\",\"price\":1}\n\n{\"label\":\n{\"label\":\"c\",\"price\":3}"
+	values, errs := collect(DecodeJSONStream[streamedMovement](context.Background(), strings.NewReader(input)))
+	expected := []streamedMovement{{Label: "a", Price: 1}, {Label: "c", Price: 3}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("DecodeJSONStream() = %v, want %v", values, expected)
+	}
+	if len(errs) != 1 {
+		t.Errorf("DecodeJSONStream() errors = %v", errs)
+	}
+}
+
+/* Nominal case, an empty stream */
+func TestDecodeJSONStreamNominalEmpty(t *testing.T) {
+	for _, input := range []string{"", " \n", "[]"} {
+		values, errs := collect(DecodeJSONStream[streamedMovement](context.Background(), strings.NewReader(input)))
+		if len(values) != 0 || len(errs) != 0 {
+			t.Errorf("DecodeJSONStream(%q) = %v, %v", input, values, errs)
+		}
+	}
+}
+
+/* Nominal case, the input is only read as fast as the elements are received */
+func TestDecodeJSONStreamNominalBackpressure(t *testing.T) {
+	reader, writer := io.Pipe()
+	items := DecodeJSONStream[int](context.Background(), reader)
+	go func() {
+		writer.Write([]byte("1\n"))
+		writer.Write([]byte("2\n"))
+		writer.Close()
+	}()
+	first := <-items
+	second := <-items
+	if _, ok := <-items; ok || first.Value != 1 || second.Value != 2 || second.Index != 1 {
+		t.Errorf("DecodeJSONStream() = %v, %v", first, second)
+	}
+}
+
+/* Error case, a malformed JSON array ends the stream */
+func TestDecodeJSONStreamErrorArray(t *testing.T) {
+	values, errs := collect(DecodeJSONStream[int](context.Background(), strings.NewReader("[1, 2 3]")))
+	if !reflect.DeepEqual(values, []int{1, 2}) || len(errs) != 1 {
+		t.Errorf("DecodeJSONStream() = %v, %v", values, errs)
+	}
+}
+
+/* Error case, the stream is cancelled */
+func TestDecodeJSONStreamErrorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	items := DecodeJSONStream[int](ctx, strings.NewReader("1\n2\n3\n"))
+	if item := <-items; item.Value != 1 {
+		t.Fatalf("DecodeJSONStream() = %v", item)
+	}
+	cancel()
+	select {
+	case <-waitClosed(items):
+	case <-time.After(time.Second):
+		t.Errorf("DecodeJSONStream() was not interrupted")
+	}
+}
+
+/* Error case, the input cannot be read */
+func TestDecodeJSONStreamErrorRead(t *testing.T) {
+	failure := errors.New("Connection reset")
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte("1\n"))
+		writer.CloseWithError(failure)
+	}()
+	values, errs := collect(DecodeJSONStream[int](context.Background(), reader))
+	if !reflect.DeepEqual(values, []int{1}) || len(errs) != 1 || !errors.Is(errs[0], failure) {
+		t.Errorf("DecodeJSONStream() = %v, %v", values, errs)
+	}
+}
+
+/* Drain the items until the channel is closed */
+func waitClosed[T any](items <-chan Item[T]) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range items {
+		}
+		close(done)
+	}()
+	return done
+}
@@ -1,5 +1,11 @@
 package serializers
 
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
 /*
 Marshaller and unmarshaller for HTTP request and response
 */
@@ -10,7 +16,48 @@ type Marshaller interface {
 	SerializationCompatibleMimetype() string
 	//Unmarshall the raw stream into the provided data
 	Deserialize([]byte) (interface{}, error)
-	//Preferred mimetypes for the input of the deserializer,
-	//TODO quality values ;q= and * asterisks are not accepted
+	//Preferred mimetypes for the input of the deserializer, as media ranges that may carry wildcards, parameters, and quality values ;q=
 	DeserializationCompatibleMimetypes() []string
 }
+
+/* Marshaller able to unmarshall straight into a caller-defined struct, optional */
+type IntoDeserializer interface {
+	//Unmarshall the raw stream into the provided pointer to a caller-defined struct
+	DeserializeInto([]byte, interface{}) error
+}
+
+/* Unmarshall the raw stream into the provided pointer with the given marshaller.
+A marshaller that is not an IntoDeserializer unmarshalls the stream with Deserialize, the result being then converted into the pointed type through JSON */
+func DeserializeInto(marshaller Marshaller, input []byte, output interface{}) error {
+	if into, ok := marshaller.(IntoDeserializer); ok {
+		return into.DeserializeInto(input, output)
+	}
+	value, err := marshaller.Deserialize(input)
+	if err != nil {
+		return err
+	}
+	//Nothing to convert if the value already has the expected type
+	target := reflect.ValueOf(output)
+	if value != nil && target.Kind() == reflect.Ptr && !target.IsNil() && reflect.TypeOf(value).AssignableTo(target.Elem().Type()) {
+		target.Elem().Set(reflect.ValueOf(value))
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, output)
+}
+
+/* Decoder of the successive values of a stream, e.g. a json.Decoder */
+type StreamDecoder interface {
+	//Unmarshall the next value of the stream into the provided pointer
+	Decode(interface{}) error
+}
+
+/* Marshaller able to decode a stream without reading it entirely first */
+type StreamMarshaller interface {
+	Marshaller
+	//Decoder reading from the stream
+	NewDecoder(io.Reader) StreamDecoder
+}
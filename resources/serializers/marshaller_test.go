@@ -0,0 +1,61 @@
+package serializers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+/* Marshaller of JSON written before DeserializeInto, for test purposes only */
+type legacyMarshaller struct{}
+
+func (marshaller *legacyMarshaller) Serialize(input interface{}) ([]byte, error) {
+	return json.Marshal(input)
+}
+
+func (marshaller *legacyMarshaller) SerializationCompatibleMimetype() string {
+	return "application/json"
+}
+
+func (marshaller *legacyMarshaller) Deserialize(input []byte) (interface{}, error) {
+	var output interface{}
+	err := json.Unmarshal(input, &output)
+	return output, err
+}
+
+func (marshaller *legacyMarshaller) DeserializationCompatibleMimetypes() []string {
+	return []string{"application/json"}
+}
+
+/* Nominal case, a marshaller that is not an IntoDeserializer decodes into a struct, or a value of the same type */
+func TestDeserializeIntoNominal(t *testing.T) {
+	type expense struct {
+		Label string  `json:"label"`
+		Price float64 `json:"price"`
+	}
+	var observed expense
+	if err := DeserializeInto(&legacyMarshaller{}, []byte(`{"label":"Supermarket","price":10.52}`), &observed); err != nil {
+		t.Fatalf("DeserializeInto() unexpected error %v", err)
+	}
+	if expected := (expense{Label: "Supermarket", Price: 10.52}); observed != expected {
+		t.Errorf("DeserializeInto() = %v, want %v", observed, expected)
+	}
+
+	var generic map[string]interface{}
+	if err := DeserializeInto(&legacyMarshaller{}, []byte(`{"tags":["a","b"]}`), &generic); err != nil || !reflect.DeepEqual(generic, map[string]interface{}{"tags": []interface{}{"a", "b"}}) {
+		t.Errorf("DeserializeInto() = %v, %v", generic, err)
+	}
+}
+
+/* Error case, the raw stream cannot be decoded, or its value does not fit the pointed type */
+func TestDeserializeIntoError(t *testing.T) {
+	var observed struct {
+		Price float64 `json:"price"`
+	}
+	if err := DeserializeInto(&legacyMarshaller{}, []byte(`{"price":`), &observed); err == nil {
+		t.Errorf("DeserializeInto() unexpected success")
+	}
+	if err := DeserializeInto(&legacyMarshaller{}, []byte(`{"price":"free"}`), &observed); err == nil {
+		t.Errorf("DeserializeInto() unexpected success")
+	}
+}
@@ -0,0 +1,187 @@
+package serializers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The value is not a valid media type, or media range
+var ErrInvalidMediaType = errors.New("Invalid media type")
+
+/* A media type as found in a Content-Type header, e.g. application/vnd.api+json; charset=utf-8,
+or a media range as found in an Accept header, e.g. application/*;q=0.8 */
+type MediaType struct {
+	//Top-level type, lowercase, * for any
+	Type string
+	//Subtype without its structured syntax suffix, lowercase, * for any
+	Subtype string
+	//Structured syntax suffix, e.g. json for application/vnd.api+json, lowercase
+	Suffix string
+	//Parameters other than the quality value, with lowercase names
+	Params map[string]string
+	//Quality value between 0 and 1, 0 means not acceptable
+	Quality float64
+}
+
+/* Parse a single media type or media range.
+Returns the parsed media type, its quality value defaults to 1 */
+func ParseMediaType(value string) (*MediaType, error) {
+	full, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMediaType, value)
+	}
+	parts := strings.SplitN(full, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 || (parts[0] == "*" && parts[1] != "*") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMediaType, value)
+	}
+	mediaType := &MediaType{
+		Type:    parts[0],
+		Subtype: parts[1],
+		Params:  map[string]string{},
+		Quality: 1,
+	}
+	if i := strings.LastIndex(mediaType.Subtype, "+"); i > 0 {
+		mediaType.Suffix = mediaType.Subtype[i+1:]
+		mediaType.Subtype = mediaType.Subtype[:i]
+	}
+	for name, paramValue := range params {
+		if name == "q" {
+			quality, err := strconv.ParseFloat(paramValue, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidMediaType, value)
+			}
+			mediaType.Quality = quality
+		} else {
+			mediaType.Params[name] = paramValue
+		}
+	}
+	return mediaType, nil
+}
+
+/* Parse a list of media types or media ranges, every value can itself be a comma-separated list as found in an Accept header.
+Returns the parsed media types ordered by decreasing quality value then specificity, the original order being kept otherwise */
+func ParseMediaTypes(values []string) ([]*MediaType, error) {
+	var mediaTypes []*MediaType
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if len(strings.TrimSpace(item)) == 0 {
+				continue
+			}
+			mediaType, err := ParseMediaType(item)
+			if err != nil {
+				return nil, err
+			}
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.SliceStable(mediaTypes, func(i, j int) bool {
+		if mediaTypes[i].Quality != mediaTypes[j].Quality {
+			return mediaTypes[i].Quality > mediaTypes[j].Quality
+		}
+		return mediaTypes[i].specificity() > mediaTypes[j].specificity()
+	})
+	return mediaTypes, nil
+}
+
+/* How precise a media range is, the full wildcard being the least specific */
+func (mediaType *MediaType) specificity() int {
+	switch {
+	case mediaType.Type == "*":
+		return 0
+	case mediaType.Subtype == "*":
+		return 1
+	case len(mediaType.Params) == 0:
+		return 2
+	default:
+		return 3
+	}
+}
+
+/* Full type/subtype, suffix included, without parameters */
+func (mediaType *MediaType) Essence() string {
+	if len(mediaType.Suffix) > 0 {
+		return mediaType.Type + "/" + mediaType.Subtype + "+" + mediaType.Suffix
+	}
+	return mediaType.Type + "/" + mediaType.Subtype
+}
+
+/* Format the media type, with its parameters and its quality value if lower than 1 */
+func (mediaType *MediaType) String() string {
+	params := make(map[string]string, len(mediaType.Params)+1)
+	for name, value := range mediaType.Params {
+		params[name] = value
+	}
+	if mediaType.Quality < 1 {
+		params["q"] = strconv.FormatFloat(mediaType.Quality, 'f', -1, 64)
+	}
+	return mime.FormatMediaType(mediaType.Essence(), params)
+}
+
+/* Does this media range include the given media type.
+Wildcards match any type or subtype, a range without suffix also matches the types with that structured syntax suffix (e.g. application/json includes application/problem+json),
+and every parameter of the range must be found with the same value in the media type, charset values being case-insensitive.
+The quality value is not taken into account */
+func (mediaType *MediaType) Includes(other *MediaType) bool {
+	if mediaType.Type != "*" && mediaType.Type != other.Type {
+		return false
+	}
+	if mediaType.Subtype != "*" {
+		sameType := mediaType.Subtype == other.Subtype && mediaType.Suffix == other.Suffix
+		structuredSyntax := len(mediaType.Suffix) == 0 && mediaType.Subtype == other.Suffix
+		if !sameType && !structuredSyntax {
+			return false
+		}
+	}
+	for name, value := range mediaType.Params {
+		otherValue, ok := other.Params[name]
+		if !ok || (otherValue != value && !(name == "charset" && strings.EqualFold(otherValue, value))) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Build the value of an Accept header out of media ranges, e.g. the ones supported by a marshaller.
+Returns the media ranges ordered by decreasing preference, along with their quality value if lower than 1 */
+func FormatAccept(mediaRanges []string) (string, error) {
+	mediaTypes, err := ParseMediaTypes(mediaRanges)
+	if err != nil {
+		return "", err
+	}
+	values := make([]string, 0, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		values = append(values, mediaType.String())
+	}
+	return strings.Join(values, ", "), nil
+}
+
+/* Check whether a response Content-Type is included in any of the given media ranges, e.g. the ones supported by a marshaller.
+The most specific range including the media type prevails, a quality value of 0 making it not acceptable.
+Returns the matching media range, nil if none */
+func Match(contentType string, mediaRanges []string) (*MediaType, error) {
+	mediaType, err := ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType.Type == "*" || mediaType.Subtype == "*" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMediaType, contentType)
+	}
+	candidates, err := ParseMediaTypes(mediaRanges)
+	if err != nil {
+		return nil, err
+	}
+	var match *MediaType
+	for _, candidate := range candidates {
+		if candidate.Includes(mediaType) && (match == nil || candidate.specificity() > match.specificity()) {
+			match = candidate
+		}
+	}
+	if match == nil || match.Quality <= 0 {
+		return nil, nil
+	}
+	return match, nil
+}
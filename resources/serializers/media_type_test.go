@@ -0,0 +1,100 @@
+package serializers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/* Nominal case, a media type with a suffix, parameters, and a quality value */
+func TestParseMediaTypeNominal(t *testing.T) {
+	observed, err := ParseMediaType("Application/Vnd.API+JSON; Charset=UTF-8; q=0.5")
+	if err != nil {
+		t.Fatalf("ParseMediaType() unexpected error %v", err)
+	}
+	expected := &MediaType{
+		Type:    "application",
+		Subtype: "vnd.api",
+		Suffix:  "json",
+		Params:  map[string]string{"charset": "UTF-8"},
+		Quality: 0.5,
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("ParseMediaType() = %v, want %v", observed, expected)
+	}
+	if observed.Essence() != "application/vnd.api+json" || observed.String() != "application/vnd.api+json; charset=UTF-8; q=0.5" {
+		t.Errorf("String() = %v", observed.String())
+	}
+}
+
+/* Error case, malformed media types */
+func TestParseMediaTypeError(t *testing.T) {
+	for _, value := range []string{"", "json", "application/", "*/json", "text/html; q=2", "text/html; q=high"} {
+		if _, err := ParseMediaType(value); !errors.Is(err, ErrInvalidMediaType) {
+			t.Errorf("ParseMediaType(%v) = %v, want %v", value, err, ErrInvalidMediaType)
+		}
+	}
+}
+
+/* Nominal case, the media ranges are ordered by preference */
+func TestFormatAcceptNominal(t *testing.T) {
+	observed, err := FormatAccept([]string{"*/*;q=0.1", "text/*;q=0.5", "application/json", "text/html;q=0.5;level=1", "application/vnd.api+json"})
+	if err != nil {
+		t.Fatalf("FormatAccept() unexpected error %v", err)
+	}
+	expected := "application/json, application/vnd.api+json, text/html; level=1; q=0.5, text/*; q=0.5, */*; q=0.1"
+	if observed != expected {
+		t.Errorf("FormatAccept() = %v, want %v", observed, expected)
+	}
+}
+
+/* Nominal case, strict matching with wildcards and structured syntax suffixes */
+func TestMatchNominal(t *testing.T) {
+	ranges := []string{"application/vnd.api+json", "application/json"}
+	for contentType, expected := range map[string]string{
+		"application/vnd.api+json":        "application/vnd.api+json",
+		"application/json; charset=utf-8": "application/json",
+		"application/problem+json":        "application/json",
+		"application/jsonp":               "",
+		"text/json":                       "",
+		"text/html":                       "",
+	} {
+		match, err := Match(contentType, ranges)
+		if err != nil {
+			t.Errorf("Match(%v) unexpected error %v", contentType, err)
+		} else if (match == nil && len(expected) > 0) || (match != nil && match.Essence() != expected) {
+			t.Errorf("Match(%v) = %v, want %v", contentType, match, expected)
+		}
+	}
+
+	//Wildcards, and parameters
+	if match, _ := Match("text/csv", []string{"text/*"}); match == nil {
+		t.Errorf("Match() no match for a wildcard subtype")
+	}
+	if match, _ := Match("text/plain; charset=ISO-8859-1", []string{"text/plain;charset=iso-8859-1"}); match == nil {
+		t.Errorf("Match() no match for a case-insensitive charset")
+	}
+	if match, _ := Match("text/plain", []string{"text/plain;charset=utf-8"}); match != nil {
+		t.Errorf("Match() = %v, want no match for a missing parameter", match)
+	}
+}
+
+/* Nominal case, the most specific range prevails, even if not acceptable */
+func TestMatchNotAcceptableNominal(t *testing.T) {
+	ranges := []string{"*/*", "text/*;q=0.5", "text/html;q=0"}
+	if match, _ := Match("text/html", ranges); match != nil {
+		t.Errorf("Match() = %v, want no match", match)
+	}
+	if match, _ := Match("text/csv", ranges); match == nil || match.Essence() != "text/*" {
+		t.Errorf("Match() = %v, want text/*", match)
+	}
+}
+
+/* Error case, the content type is not a media type */
+func TestMatchError(t *testing.T) {
+	for _, contentType := range []string{"json", "*/*", "text/*"} {
+		if _, err := Match(contentType, []string{"*/*"}); !errors.Is(err, ErrInvalidMediaType) {
+			t.Errorf("Match(%v) = %v, want %v", contentType, err, ErrInvalidMediaType)
+		}
+	}
+}
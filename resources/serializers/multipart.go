@@ -0,0 +1,211 @@
+package serializers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// The content of a part has already been consumed and cannot be read again
+var ErrPartNotReplayable = errors.New("The content of the part cannot be read again")
+
+// default content type of the file parts
+const defaultPartContentType = "application/octet-stream"
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+/* A single part of a multipart/form-data body */
+type part struct {
+	name string
+	//Empty for a regular field
+	filename    string
+	contentType string
+	//Opens the content of the part, every time the body is built
+	open func() (io.ReadCloser, error)
+	//Can the content be opened more than once
+	replayable bool
+}
+
+/* Builder of a multipart/form-data request body, made of fields and files.
+The body is streamed, the files are read while the request is sent, not buffered in memory */
+type Multipart struct {
+	parts []*part
+	//Boundary between the parts, the same for every build of the body
+	boundary string
+}
+
+/* Multipart c'tor */
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+	}
+}
+
+/* Add a regular field.
+Returns the updated body */
+func (body *Multipart) WithField(name string, value string) *Multipart {
+	body.parts = append(body.parts, &part{
+		name: name,
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(value)), nil
+		},
+		replayable: true,
+	})
+	return body
+}
+
+/* Add a file read from content, application/octet-stream being the default contentType.
+If content is an io.Seeker it is rewound every time the body is built, otherwise the body can be sent only once.
+content is not closed.
+Returns the updated body */
+func (body *Multipart) WithFile(name string, filename string, contentType string, content io.Reader) *Multipart {
+	seeker, replayable := content.(io.Seeker)
+	var offset int64
+	if replayable {
+		var err error
+		offset, err = seeker.Seek(0, io.SeekCurrent)
+		replayable = err == nil
+	}
+	used := false
+	var mutex sync.Mutex
+	body.parts = append(body.parts, &part{
+		name:        name,
+		filename:    filename,
+		contentType: contentType,
+		open: func() (io.ReadCloser, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if replayable {
+				if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+					return nil, err
+				}
+			} else if used {
+				return nil, fmt.Errorf("%w: %s", ErrPartNotReplayable, name)
+			}
+			used = true
+			return ioutil.NopCloser(content), nil
+		},
+		replayable: replayable,
+	})
+	return body
+}
+
+/* Add a file whose content is opened by open every time the body is built, e.g. with os.Open(), and closed once read.
+application/octet-stream is the default contentType.
+Returns the updated body */
+func (body *Multipart) WithFileOpener(name string, filename string, contentType string, open func() (io.ReadCloser, error)) *Multipart {
+	body.parts = append(body.parts, &part{
+		name:        name,
+		filename:    filename,
+		contentType: contentType,
+		open:        open,
+		replayable:  true,
+	})
+	return body
+}
+
+/* Media type of the body, boundary included */
+func (body *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + body.boundary
+}
+
+/* Can the body be built more than once, e.g. in order to retry sending it */
+func (body *Multipart) Replayable() bool {
+	for _, part := range body.parts {
+		if !part.replayable {
+			return false
+		}
+	}
+	return true
+}
+
+/* Stream the body.
+The parts are only opened once the body is first read, and the body must be closed.
+Returns the body reader */
+func (body *Multipart) Reader() io.ReadCloser {
+	return &multipartReader{body: body}
+}
+
+/* Write every part into the writer, in order */
+func (body *Multipart) write(writer io.Writer) error {
+	multipartWriter := multipart.NewWriter(writer)
+	if err := multipartWriter.SetBoundary(body.boundary); err != nil {
+		return err
+	}
+	for _, part := range body.parts {
+		header := textproto.MIMEHeader{}
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.name))
+		if len(part.filename) > 0 {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(part.filename))
+			contentType := part.contentType
+			if len(contentType) == 0 {
+				contentType = defaultPartContentType
+			}
+			header.Set("Content-Type", contentType)
+		} else if len(part.contentType) > 0 {
+			header.Set("Content-Type", part.contentType)
+		}
+		header.Set("Content-Disposition", disposition)
+		partWriter, err := multipartWriter.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		content, err := part.open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(partWriter, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return multipartWriter.Close()
+}
+
+/* Lazily stream a multipart body through a pipe */
+type multipartReader struct {
+	body   *Multipart
+	pipe   *io.PipeReader
+	once   sync.Once
+	closed bool
+	mutex  sync.Mutex
+}
+
+/* Start writing the body into the pipe, once */
+func (reader *multipartReader) start() {
+	reader.once.Do(func() {
+		pipeReader, pipeWriter := io.Pipe()
+		reader.pipe = pipeReader
+		go func() {
+			pipeWriter.CloseWithError(reader.body.write(pipeWriter))
+		}()
+	})
+}
+
+func (reader *multipartReader) Read(p []byte) (int, error) {
+	reader.mutex.Lock()
+	if reader.closed {
+		reader.mutex.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	reader.start()
+	reader.mutex.Unlock()
+	return reader.pipe.Read(p)
+}
+
+/* Stop the streaming, if started */
+func (reader *multipartReader) Close() error {
+	reader.mutex.Lock()
+	defer reader.mutex.Unlock()
+	reader.closed = true
+	if reader.pipe != nil {
+		return reader.pipe.Close()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package serializers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+/* Read every part of a multipart body.
+Returns the content of the parts, along with their file name and content type */
+func readMultipart(t *testing.T, contentType string, body io.Reader) []string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("ContentType() unexpected error %v", err)
+	}
+	reader := multipart.NewReader(body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		} else if err != nil {
+			t.Fatalf("Reader() unexpected error %v", err)
+		}
+		content, _ := ioutil.ReadAll(part)
+		parts = append(parts, part.FormName()+"|"+part.FileName()+"|"+part.Header.Get("Content-Type")+"|"+string(content))
+	}
+}
+
+/* Nominal case, fields and files are streamed, the body can be read again */
+func TestMultipartNominal(t *testing.T) {
+	opened := 0
+	body := NewMultipart().
+		WithField("name", "julien").
+		WithFile("avatar", "me.png", "image/png", bytes.NewReader([]byte("PNG"))).
+		WithFileOpener("cv", `my "cv".txt`, "", func() (io.ReadCloser, error) {
+			opened++
+			return ioutil.NopCloser(strings.NewReader("hello")), nil
+		})
+	if !body.Replayable() {
+		t.Fatalf("Replayable() = false")
+	}
+	expected := []string{"name|||julien", "avatar|me.png|image/png|PNG", `cv|my "cv".txt|application/octet-stream|hello`}
+	for i := 0; i < 2; i++ {
+		reader := body.Reader()
+		observed := readMultipart(t, body.ContentType(), reader)
+		reader.Close()
+		if strings.Join(observed, ",") != strings.Join(expected, ",") {
+			t.Errorf("Reader() = %v, want %v", observed, expected)
+		}
+	}
+	if opened != 2 {
+		t.Errorf("WithFileOpener() opened %v times, want 2", opened)
+	}
+}
+
+/* Nominal case, nothing is opened until the body is read */
+func TestMultipartLazyNominal(t *testing.T) {
+	opened := false
+	body := NewMultipart().WithFileOpener("cv", "cv.txt", "text/plain", func() (io.ReadCloser, error) {
+		opened = true
+		return ioutil.NopCloser(strings.NewReader("hello")), nil
+	})
+	body.Reader().Close()
+	if opened {
+		t.Errorf("Reader() opened a file without being read")
+	}
+}
+
+/* Error case, a one-shot reader can only be streamed once */
+func TestMultipartError(t *testing.T) {
+	body := NewMultipart().WithFile("log", "log.txt", "text/plain", io.LimitReader(strings.NewReader("content"), 100))
+	if body.Replayable() {
+		t.Fatalf("Replayable() = true")
+	}
+	reader := body.Reader()
+	readMultipart(t, body.ContentType(), reader)
+	reader.Close()
+	if _, err := ioutil.ReadAll(body.Reader()); !errors.Is(err, ErrPartNotReplayable) {
+		t.Errorf("Reader() = %v, want %v", err, ErrPartNotReplayable)
+	}
+}
@@ -0,0 +1,188 @@
+package serializers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// No marshaller can handle the media type
+var ErrNoMarshaller = errors.New("No marshaller for the media type")
+
+/* Marshaller delegating the (de)serialization to other marshallers depending on the media type */
+type Selector interface {
+	Marshaller
+	//Marshaller able to decode a response with the given content type
+	Decoder(contentType string) (Marshaller, error)
+	//Marshaller able to encode a request into the given media type
+	Encoder(mediaType string) (Marshaller, error)
+}
+
+/* A marshaller registered for a media range */
+type registration struct {
+	mediaRange *MediaType
+	marshaller Marshaller
+}
+
+/* Set of marshallers keyed by media type, the first registered one being the default */
+type registry struct {
+	registrations []registration
+}
+
+/* registry c'tor.
+Every marshaller is registered for its own media types, see Register() */
+func NewRegistry(marshallers ...Marshaller) *registry {
+	registry := &registry{}
+	for _, marshaller := range marshallers {
+		registry.Register(marshaller)
+	}
+	return registry
+}
+
+/* Register a marshaller for the given media ranges, or by default for the media types it can (de)serialize.
+A media range that cannot be parsed is ignored.
+Returns the updated registry */
+func (registry *registry) Register(marshaller Marshaller, mediaRanges ...string) *registry {
+	if marshaller == nil {
+		return registry
+	}
+	if len(mediaRanges) == 0 {
+		mediaRanges = append([]string{marshaller.SerializationCompatibleMimetype()}, marshaller.DeserializationCompatibleMimetypes()...)
+	}
+	for _, value := range mediaRanges {
+		mediaRange, err := ParseMediaType(value)
+		if err != nil {
+			continue
+		}
+		registry.registrations = append(registry.registrations, registration{mediaRange: mediaRange, marshaller: marshaller})
+	}
+	return registry
+}
+
+/* Find the marshaller registered for the most specific media range including the media type, the first registered one wins a tie.
+Returns ErrNoMarshaller if none */
+func (registry *registry) lookup(value string) (Marshaller, error) {
+	mediaType, err := ParseMediaType(value)
+	if err != nil {
+		return nil, err
+	}
+	var match *registration
+	for i, candidate := range registry.registrations {
+		if candidate.mediaRange.Includes(mediaType) && (match == nil || candidate.mediaRange.specificity() > match.mediaRange.specificity()) {
+			match = &registry.registrations[i]
+		}
+	}
+	if match == nil || match.mediaRange.Quality <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMarshaller, value)
+	}
+	return match.marshaller, nil
+}
+
+func (registry *registry) Decoder(contentType string) (Marshaller, error) {
+	return registry.lookup(contentType)
+}
+
+func (registry *registry) Encoder(mediaType string) (Marshaller, error) {
+	return registry.lookup(mediaType)
+}
+
+/* The first registered marshaller */
+func (registry *registry) fallback() (Marshaller, error) {
+	if len(registry.registrations) == 0 {
+		return nil, ErrNoMarshaller
+	}
+	return registry.registrations[0].marshaller, nil
+}
+
+func (registry *registry) Serialize(input interface{}) ([]byte, error) {
+	marshaller, err := registry.fallback()
+	if err != nil {
+		return nil, err
+	}
+	return marshaller.Serialize(input)
+}
+
+func (registry *registry) SerializationCompatibleMimetype() string {
+	marshaller, err := registry.fallback()
+	if err != nil {
+		return ""
+	}
+	return marshaller.SerializationCompatibleMimetype()
+}
+
+func (registry *registry) Deserialize(input []byte) (interface{}, error) {
+	marshaller, err := registry.fallback()
+	if err != nil {
+		return nil, err
+	}
+	return marshaller.Deserialize(input)
+}
+
+func (registry *registry) DeserializeInto(input []byte, output interface{}) error {
+	marshaller, err := registry.fallback()
+	if err != nil {
+		return err
+	}
+	return DeserializeInto(marshaller, input, output)
+}
+
+/* All the registered media ranges, without duplicates */
+func (registry *registry) DeserializationCompatibleMimetypes() []string {
+	var mediaRanges []string
+	known := map[string]bool{}
+	for _, registration := range registry.registrations {
+		value := registration.mediaRange.String()
+		if !known[value] {
+			known[value] = true
+			mediaRanges = append(mediaRanges, value)
+		}
+	}
+	return mediaRanges
+}
+
+/* Find the marshaller able to decode a response with the given content type, delegating to the marshaller if it is a Selector.
+An empty content type is decoded by the marshaller itself.
+A charset parameter other than UTF-8 makes the response be converted into UTF-8 before being decoded.
+Returns ErrNoMarshaller if the content type is not supported, ErrUnsupportedCharset if its charset is not */
+func Decoder(marshaller Marshaller, contentType string) (Marshaller, error) {
+	if len(contentType) == 0 {
+		return marshaller, nil
+	}
+	if selector, ok := marshaller.(Selector); ok {
+		decoder, err := selector.Decoder(contentType)
+		if err != nil {
+			return nil, err
+		}
+		return withCharset(decoder, contentType)
+	}
+	match, err := Match(contentType, marshaller.DeserializationCompatibleMimetypes())
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoMarshaller, contentType)
+	}
+	return withCharset(marshaller, contentType)
+}
+
+/* Find the marshaller able to encode a request into the given media type, delegating to the marshaller if it is a Selector.
+An empty media type is encoded by the marshaller itself.
+Returns ErrNoMarshaller if the media type is not supported */
+func Encoder(marshaller Marshaller, mediaType string) (Marshaller, error) {
+	if len(mediaType) == 0 {
+		return marshaller, nil
+	}
+	if selector, ok := marshaller.(Selector); ok {
+		return selector.Encoder(mediaType)
+	}
+	match, err := Match(mediaType, append([]string{marshaller.SerializationCompatibleMimetype()}, marshaller.DeserializationCompatibleMimetypes()...))
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoMarshaller, mediaType)
+	}
+	return marshaller, nil
+}
+
+// Make sure the registry can be used as a marshaller
+var _ Selector = (*registry)(nil)
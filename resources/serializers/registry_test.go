@@ -0,0 +1,106 @@
+package serializers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/* Marshaller of plain text, for test purposes only */
+type textMarshaller struct{}
+
+func (marshaller *textMarshaller) Serialize(input interface{}) ([]byte, error) {
+	return []byte(input.(string)), nil
+}
+
+func (marshaller *textMarshaller) SerializationCompatibleMimetype() string {
+	return "text/plain"
+}
+
+func (marshaller *textMarshaller) Deserialize(input []byte) (interface{}, error) {
+	return string(input), nil
+}
+
+func (marshaller *textMarshaller) DeserializationCompatibleMimetypes() []string {
+	return []string{"text/plain", "text/*;q=0.5"}
+}
+
+/* Nominal case, the marshaller is picked according to the media type */
+func TestRegistryNominal(t *testing.T) {
+	json := NewJsonMarshaller()
+	text := &textMarshaller{}
+	registry := NewRegistry(json, text)
+
+	for contentType, expected := range map[string]Marshaller{
+		"application/json; charset=utf-8": json,
+		"application/problem+json":        json,
+		"text/plain":                      text,
+		"text/csv":                        text,
+	} {
+		observed, err := registry.Decoder(contentType)
+		if err != nil || observed != expected {
+			t.Errorf("Decoder(%v) = %v, %v", contentType, observed, err)
+		}
+	}
+	if observed, err := registry.Encoder("text/plain"); err != nil || observed != text {
+		t.Errorf("Encoder() = %v, %v", observed, err)
+	}
+
+	//The first registered marshaller is the default one
+	if observed := registry.SerializationCompatibleMimetype(); observed != json.SerializationCompatibleMimetype() {
+		t.Errorf("SerializationCompatibleMimetype() = %v", observed)
+	}
+	expected := []string{"application/vnd.api+json", "application/json", "text/plain", "text/*; q=0.5"}
+	if observed := registry.DeserializationCompatibleMimetypes(); !reflect.DeepEqual(observed, expected) {
+		t.Errorf("DeserializationCompatibleMimetypes() = %v, want %v", observed, expected)
+	}
+}
+
+/* Nominal case, a marshaller registered for specific media ranges */
+func TestRegistryRegisterNominal(t *testing.T) {
+	json := NewJsonMarshaller()
+	registry := NewRegistry().Register(json, "application/geo+json", "not a media type")
+
+	if observed, err := registry.Decoder("application/geo+json"); err != nil || observed != json {
+		t.Errorf("Decoder() = %v, %v", observed, err)
+	}
+	if _, err := registry.Decoder("application/json"); !errors.Is(err, ErrNoMarshaller) {
+		t.Errorf("Decoder() = %v, want %v", err, ErrNoMarshaller)
+	}
+}
+
+/* Error case, no marshaller for the media type */
+func TestRegistryError(t *testing.T) {
+	registry := NewRegistry(NewJsonMarshaller())
+	if _, err := registry.Decoder("application/xml"); !errors.Is(err, ErrNoMarshaller) {
+		t.Errorf("Decoder() = %v, want %v", err, ErrNoMarshaller)
+	}
+	if _, err := NewRegistry().Serialize("{}"); !errors.Is(err, ErrNoMarshaller) {
+		t.Errorf("Serialize() = %v, want %v", err, ErrNoMarshaller)
+	}
+}
+
+/* Nominal case, a regular marshaller handles its own media types */
+func TestEncoderDecoderNominal(t *testing.T) {
+	json := NewJsonMarshaller()
+	if observed, err := Decoder(json, ""); err != nil || observed != json {
+		t.Errorf("Decoder() = %v, %v", observed, err)
+	}
+	if observed, err := Decoder(json, "application/json"); err != nil || observed != json {
+		t.Errorf("Decoder() = %v, %v", observed, err)
+	}
+	if observed, err := Encoder(json, "application/vnd.api+json"); err != nil || observed != json {
+		t.Errorf("Encoder() = %v, %v", observed, err)
+	}
+	if _, err := Encoder(json, "text/plain"); !errors.Is(err, ErrNoMarshaller) {
+		t.Errorf("Encoder() = %v, want %v", err, ErrNoMarshaller)
+	}
+	//The response is converted according to its charset
+	text, err := Decoder(&textMarshaller{}, "text/plain; charset=windows-1252")
+	if err != nil {
+		t.Fatalf("Decoder() unexpected error %v", err)
+	}
+	if observed, err := text.Deserialize([]byte("caf\xe9 \x80")); err != nil || observed != "café €" {
+		t.Errorf("Deserialize() = %q, %v, want %q", observed, err, "café €")
+	}
+}
@@ -0,0 +1,108 @@
+package serializers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+/* Generic XML element, used when the structure of the document is unknown.
+The namespaces of the element and of its attributes are kept in their xml.Name */
+type Element struct {
+	XMLName xml.Name
+	//Attributes, namespace declarations included
+	Attrs []xml.Attr `xml:",any,attr"`
+	//Child elements, in order
+	Children []*Element `xml:",any"`
+	//Character data directly within the element
+	Text string `xml:",chardata"`
+}
+
+/* First child element with the given local name, whatever its namespace, nil if none */
+func (element *Element) Child(name string) *Element {
+	for _, child := range element.Children {
+		if child.XMLName.Local == name {
+			return child
+		}
+	}
+	return nil
+}
+
+/* Value of the attribute with the given local name, whatever its namespace, and whether it was found */
+func (element *Element) Attr(name string) (string, bool) {
+	for _, attr := range element.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+/* (Un)marshaller for XML, driven by the encoding/xml struct tags */
+type xmlMarshaller struct {
+	//Charset of the documents given by their media type, which prevails over the encoding they declare, empty if none
+	charset string
+}
+
+/* Marshaller c'tor */
+func NewXmlMarshaller() *xmlMarshaller {
+	return &xmlMarshaller{}
+}
+
+func (marshaller *xmlMarshaller) Serialize(input interface{}) ([]byte, error) {
+	encoded, err := xml.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), encoded...), nil
+}
+
+func (marshaller *xmlMarshaller) SerializationCompatibleMimetype() string {
+	return "application/xml"
+}
+
+/* Decode the document into a generic tree of *Element */
+func (marshaller *xmlMarshaller) Deserialize(input []byte) (interface{}, error) {
+	output := &Element{}
+	if err := marshaller.DeserializeInto(input, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+/* Decode the document into the provided pointer, a document declaring a non-UTF-8 encoding is converted first if supported */
+func (marshaller *xmlMarshaller) DeserializeInto(input []byte, output interface{}) error {
+	return marshaller.NewDecoder(bytes.NewReader(input)).Decode(output)
+}
+
+/* Decoder of the successive documents of a stream, a document declaring a non-UTF-8 encoding is converted first if supported */
+func (marshaller *xmlMarshaller) NewDecoder(input io.Reader) StreamDecoder {
+	if len(marshaller.charset) == 0 {
+		decoder := xml.NewDecoder(input)
+		decoder.CharsetReader = charsetReader
+		return decoder
+	}
+	//The charset was checked beforehand
+	converted, _ := charsetReader(marshaller.charset, input)
+	decoder := xml.NewDecoder(converted)
+	//The document is already converted, whatever it declares
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+/* Same marshaller, for documents whose charset is given by their media type */
+func (marshaller *xmlMarshaller) withCharset(charset string) Marshaller {
+	return &xmlMarshaller{charset: charset}
+}
+
+func (marshaller *xmlMarshaller) DeserializationCompatibleMimetypes() []string {
+	return []string{
+		"application/xml",
+		"text/xml",
+	}
+}
+
+// Make sure the marshaller can decode streams
+var _ StreamMarshaller = (*xmlMarshaller)(nil)
@@ -0,0 +1,183 @@
+package serializers
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type xmlMovement struct {
+	Label string  `xml:"label"`
+	Price float32 `xml:"price,attr"`
+}
+
+type xmlAccountBalance struct {
+	XMLName   xml.Name      `xml:"urn:bank balance"`
+	Owner     string        `xml:"owner,attr"`
+	Movements []xmlMovement `xml:"movement"`
+	Total     float32       `xml:"total"`
+}
+
+/* Nominal case, marshalling and unmarshalling XML data with struct tags */
+func TestXmlMarshallerNominalMarshallingUnmarshalling(t *testing.T) {
+	data := xmlAccountBalance{
+		Owner: "Simon",
+		Movements: []xmlMovement{
+			{Label: "Supermarket", Price: 10.52},
+			{Label: "Gas station", Price: 60.1},
+		},
+		Total: 70.62,
+	}
+	marshaller := NewXmlMarshaller()
+	encoded, err := marshaller.Serialize(data)
+	if err != nil {
+		t.Fatalf("Serialize() unexpected error %v", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<balance xmlns="urn:bank" owner="Simon"><movement price="10.52"><label>Supermarket</label></movement><movement price="60.1"><label>Gas station</label></movement><total>70.62</total></balance>`
+	if string(encoded) != expected {
+		t.Errorf("Serialize() = %v, want %v", string(encoded), expected)
+	}
+
+	var decoded xmlAccountBalance
+	if err = marshaller.DeserializeInto(encoded, &decoded); err != nil {
+		t.Fatalf("DeserializeInto() unexpected error %v", err)
+	}
+	data.XMLName = xml.Name{Space: "urn:bank", Local: "balance"}
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("DeserializeInto() = %v, want %v", decoded, data)
+	}
+}
+
+/* Nominal case, unmarshalling XML data into a generic tree, namespaces included */
+func TestXmlMarshallerNominalGenericTree(t *testing.T) {
+	document := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:geo="http://www.georss.org/georss"><title type="text">News</title><geo:point>45.2 4.8</geo:point></feed>`
+	decoded, err := NewXmlMarshaller().Deserialize([]byte(document))
+	if err != nil {
+		t.Fatalf("Deserialize() unexpected error %v", err)
+	}
+	root, ok := decoded.(*Element)
+	if !ok {
+		t.Fatalf("Deserialize() = %T, want *Element", decoded)
+	}
+	if root.XMLName.Space != "http://www.w3.org/2005/Atom" || root.XMLName.Local != "feed" || len(root.Children) != 2 {
+		t.Fatalf("Deserialize() = %+v", root)
+	}
+	title := root.Child("title")
+	if title == nil {
+		t.Fatalf("Child() found no title")
+	}
+	if kind, _ := title.Attr("type"); title.Text != "News" || kind != "text" {
+		t.Errorf("Child() = %+v", title)
+	}
+	point := root.Child("point")
+	if point == nil || point.XMLName.Space != "http://www.georss.org/georss" || point.Text != "45.2 4.8" {
+		t.Errorf("Child() = %+v", point)
+	}
+	if root.Child("summary") != nil {
+		t.Errorf("Child() found a missing element")
+	}
+}
+
+/* Nominal case, unmarshalling XML documents that are not encoded in UTF-8 */
+func TestXmlMarshallerNominalCharsets(t *testing.T) {
+	type note struct {
+		Text string `xml:"text"`
+	}
+	for charset, expected := range map[string]string{
+		"ISO-8859-1":   "café \u0080",
+		"windows-1252": "café €",
+		"US-ASCII":     "caf� �",
+		"UTF-8":        "café €",
+	} {
+		text := "caf\xe9 \x80"
+		if charset == "UTF-8" {
+			text = "café €"
+		}
+		document := `<?xml version="1.0" encoding="` + charset + `"?><note><text>` + text + `</text></note>`
+		var decoded note
+		if err := NewXmlMarshaller().DeserializeInto([]byte(document), &decoded); err != nil {
+			t.Errorf("DeserializeInto(%v) unexpected error %v", charset, err)
+		} else if decoded.Text != expected {
+			t.Errorf("DeserializeInto(%v) = %q, want %q", charset, decoded.Text, expected)
+		}
+	}
+}
+
+/* Nominal case, unmarshalling XML documents whose charset is only given by their media type, which prevails over the one they declare */
+func TestXmlMarshallerNominalMediaTypeCharset(t *testing.T) {
+	type note struct {
+		Text string `xml:"text"`
+	}
+	for _, document := range []string{
+		"<note><text>caf\xe9</text></note>",
+		`<?xml version="1.0" encoding="ISO-8859-1"?><note><text>caf` + "\xe9" + `</text></note>`,
+		`<?xml version="1.0" encoding="UTF-8"?><note><text>caf` + "\xe9" + `</text></note>`,
+	} {
+		marshaller, err := Decoder(NewXmlMarshaller(), "text/xml; charset=ISO-8859-1")
+		if err != nil {
+			t.Fatalf("Decoder() unexpected error %v", err)
+		}
+		var decoded note
+		if err := DeserializeInto(marshaller, []byte(document), &decoded); err != nil {
+			t.Errorf("DeserializeInto(%q) unexpected error %v", document, err)
+		} else if decoded.Text != "café" {
+			t.Errorf("DeserializeInto(%q) = %q, want %q", document, decoded.Text, "café")
+		}
+		decoded = note{}
+		if err := marshaller.(StreamMarshaller).NewDecoder(strings.NewReader(document)).Decode(&decoded); err != nil || decoded.Text != "café" {
+			t.Errorf("NewDecoder(%q) = %q, %v, want %q", document, decoded.Text, err, "café")
+		}
+	}
+	if _, err := Decoder(NewXmlMarshaller(), "text/xml; charset=EBCDIC"); !errors.Is(err, ErrUnsupportedCharset) {
+		t.Errorf("Decoder() = %v, want %v", err, ErrUnsupportedCharset)
+	}
+}
+
+/* Error case, unsupported charset */
+func TestXmlMarshallerErrorCharset(t *testing.T) {
+	document := `<?xml version="1.0" encoding="EBCDIC"?><note/>`
+	if _, err := NewXmlMarshaller().Deserialize([]byte(document)); !errors.Is(err, ErrUnsupportedCharset) {
+		t.Errorf("Deserialize() = %v, want %v", err, ErrUnsupportedCharset)
+	}
+}
+
+/* Error case, unmarshalling invalid XML data */
+func TestXmlMarshallerErrorUnmarshalling(t *testing.T) {
+	if _, err := NewXmlMarshaller().Deserialize([]byte("<note><text>unclosed</note>")); err == nil {
+		t.Errorf("Deserialize() unexpected success")
+	}
+}
+
+/* Nominal case, compatible XML mime types */
+func TestXmlMarshallerNominalCompatibleMimeTypes(t *testing.T) {
+	marshaller := NewXmlMarshaller()
+	if marshaller.SerializationCompatibleMimetype() != "application/xml" {
+		t.Errorf("SerializationCompatibleMimetype() = %v", marshaller.SerializationCompatibleMimetype())
+	}
+	for _, contentType := range []string{"application/xml", "text/xml; charset=ISO-8859-1", "application/atom+xml"} {
+		if match, _ := Match(contentType, marshaller.DeserializationCompatibleMimetypes()); match == nil {
+			t.Errorf("DeserializationCompatibleMimetypes() = %v, want %v", marshaller.DeserializationCompatibleMimetypes(), contentType)
+		}
+	}
+}
+
+/* Nominal case, decoding a stream of XML elements */
+func TestXmlMarshallerNominalStreamDecoding(t *testing.T) {
+	decoder := NewXmlMarshaller().NewDecoder(strings.NewReader(`<movement price="1"><label>a</label></movement><movement price="2"><label>b</label></movement>`))
+	var labels []string
+	for {
+		var movement xmlMovement
+		if err := decoder.Decode(&movement); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode() unexpected error %v", err)
+		}
+		labels = append(labels, movement.Label)
+	}
+	if !reflect.DeepEqual(labels, []string{"a", "b"}) {
+		t.Errorf("Decode() = %v", labels)
+	}
+}
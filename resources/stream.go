@@ -0,0 +1,88 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+/* Raw body of a streamed response, to be read then closed by the caller.
+Closing it releases the connection, and the context of the call */
+type Stream struct {
+	io.ReadCloser
+	//Headers of the response
+	Header http.Header
+	//Marshaller of the resource
+	marshaller serializers.Marshaller
+}
+
+/* Decoder of the successive values of the body, picked according to the content type of the response among the marshallers of the resource.
+Returns ErrUnsupportedMediaType if no marshaller can decode the body as a stream */
+func (stream *Stream) Decoder() (serializers.StreamDecoder, error) {
+	contentType := stream.Header.Get("Content-Type")
+	marshaller, err := serializers.Decoder(stream.marshaller, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+	streamMarshaller, ok := marshaller.(serializers.StreamMarshaller)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s cannot be streamed", ErrUnsupportedMediaType, contentType)
+	}
+	return streamMarshaller.NewDecoder(stream), nil
+}
+
+/* Make an HTTP request for a prepared streamed Request.
+Returns the raw response body, which must be closed, and the HTTP status code (0 means unknown) */
+type StreamFunc func() (*Stream, int, error)
+
+/*
+Prepare a request for a given action whose response body is streamed rather than read in memory, see Request().
+The body to send can be an io.Reader, which is streamed as is, see WithEncoding() to set its media type.
+Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function, or the reading of the body
+*/
+func (resource *resource) Stream(verb string, urlParameters interface{}, body interface{}, options ...RequestOption) (StreamFunc, context.CancelFunc, error) {
+	return resource.StreamContext(context.Background(), verb, urlParameters, body, options...)
+}
+
+/*
+Prepare a streamed request for a given action bound to the caller context, see Stream() and RequestContext().
+The overall timeout of the resource includes the reading of the body.
+Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function, or the reading of the body
+*/
+func (resource *resource) StreamContext(ctx context.Context, verb string, urlParameters interface{}, body interface{}, options ...RequestOption) (StreamFunc, context.CancelFunc, error) {
+
+	settings := newRequestOptions(options)
+	actual := resource.override(settings)
+	request, cancel, err := actual.prepare(ctx, verb, urlParameters, body, settings)
+	if err != nil {
+		return nil, cancel, err
+	}
+
+	return func() (*Stream, int, error) {
+		return actual.stream(request)
+	}, cancel, nil
+
+}
+
+/* Make an HTTP request with the resource client for the specified prepared request, without reading the response body.
+Returns the raw response body, the HTTP status code, 0 means we don't have one to provide */
+func (resource *resource) stream(request *http.Request) (*Stream, int, error) {
+	response, tries, err := resource.send(request, resource.timeout, resource.attemptTimeout, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	//Unsuccessful responses carry an error payload, not the expected body
+	if resource.httpErrors && !isSuccessful(response.StatusCode) {
+		return nil, response.StatusCode, resource.readHTTPError(request, response, tries)
+	}
+
+	return &Stream{
+		ReadCloser: response.Body,
+		Header:     response.Header,
+		marshaller: resource.marshaller,
+	}, response.StatusCode, nil
+}
@@ -0,0 +1,178 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+/* Nominal case, a raw body is uploaded, and the raw response body handed over until closed */
+func TestResourceStreamNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/export")
+	mockClient := mocks.Client{}
+	released := make(chan struct{})
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if observed := req.Header.Get("Content-Type"); observed != "text/csv" {
+			t.Errorf("Stream() Content-Type = %v, want text/csv", observed)
+		}
+		uploaded, _ := io.ReadAll(req.Body)
+		go func() {
+			<-req.Context().Done()
+			close(released)
+		}()
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/csv"}},
+			Body:       io.NopCloser(strings.NewReader("id\n" + string(uploaded))),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	//Not a buffer, in order to be streamed
+	upload := io.MultiReader(strings.NewReader("1\n"), strings.NewReader("2\n"))
+	call, _, err := res.Stream("POST", nil, upload, WithEncoding("text/csv"))
+	if err != nil {
+		t.Fatalf("Stream() unexpected error %v", err)
+	}
+	stream, code, err := call()
+	if err != nil || code != 200 {
+		t.Fatalf("call() = %v, %v", code, err)
+	}
+	select {
+	case <-released:
+		t.Fatalf("call() released the context before the body is closed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	content, _ := io.ReadAll(stream)
+	if string(content) != "id\n1\n2\n" || stream.Header.Get("Content-Type") != "text/csv" {
+		t.Errorf("call() = %v", string(content))
+	}
+	stream.Close()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Errorf("Close() did not release the context")
+	}
+}
+
+/* Nominal case, the response body is decoded value by value */
+func TestResourceStreamDecoderNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/events")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id":1} {"id":2}`)),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, _ := res.Stream("GET", nil, nil)
+	stream, _, err := call()
+	if err != nil {
+		t.Fatalf("call() unexpected error %v", err)
+	}
+	defer stream.Close()
+	decoder, err := stream.Decoder()
+	if err != nil {
+		t.Fatalf("Decoder() unexpected error %v", err)
+	}
+	var ids []int
+	for {
+		var event struct {
+			ID int `json:"id"`
+		}
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode() unexpected error %v", err)
+		}
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Decode() = %v", ids)
+	}
+}
+
+/* Error case, the response body cannot be decoded as a stream */
+func TestResourceStreamDecoderError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/export")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"text/csv"}},
+			Body:       io.NopCloser(strings.NewReader("id\n1\n")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, _ := res.Stream("GET", nil, nil)
+	stream, _, _ := call()
+	defer stream.Close()
+	if _, err := stream.Decoder(); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("Decoder() = %v, want %v", err, ErrUnsupportedMediaType)
+	}
+}
+
+/* Error case, an unsuccessful streamed response is reported as an HTTPError */
+func TestResourceStreamHTTPError(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/export")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 404,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"missing"}`)),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient).WithHTTPErrors(true)
+
+	call, _, _ := res.Stream("GET", nil, nil)
+	stream, code, err := call()
+	var httpErr *HTTPError
+	if stream != nil || code != 404 || !errors.As(err, &httpErr) || string(httpErr.Body) != `{"error":"missing"}` {
+		t.Errorf("call() = %v, %v, %v", stream, code, err)
+	}
+}
+
+/* Nominal case, the elements of a newline-delimited JSON response are received one by one */
+func TestResourceStreamItemsNominal(t *testing.T) {
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/export")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/x-ndjson"}},
+			Body:       io.NopCloser(strings.NewReader("{\"id\":1}\n{\"id\":2}\n")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	ctx := context.Background()
+	call, _, _ := res.StreamContext(ctx, "GET", nil, nil)
+	stream, _, err := call()
+	if err != nil {
+		t.Fatalf("call() unexpected error %v", err)
+	}
+	defer stream.Close()
+	var ids []int
+	for item := range serializers.DecodeJSONStream[struct{ ID int }](ctx, stream) {
+		if item.Err != nil {
+			t.Fatalf("DecodeJSONStream() unexpected error %v", item.Err)
+		}
+		ids = append(ids, item.Value.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("DecodeJSONStream() = %v", ids)
+	}
+}
@@ -0,0 +1,49 @@
+package resources
+
+import (
+	"context"
+)
+
+/* Make an HTTP request for a prepared typed Request.
+Returns the response body decoded into a new instance of the struct we expect, and the HTTP status code (0 means unknown) */
+type TypedCallFunc[Resp any] func() (*Resp, int, error)
+
+/*
+Prepare a typed request for a given action on a resource, see resource.Request().
+Unlike Request(), the request body and the response body are typed at compile-time and the response is decoded straight into Resp by the resource marshaller.
+Use interface{} as Req when no body is to be sent.
+Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function
+*/
+func Do[Req any, Resp any](resource *resource, verb string, urlParameters interface{}, body *Req, options ...RequestOption) (TypedCallFunc[Resp], context.CancelFunc, error) {
+	return DoContext[Req, Resp](context.Background(), resource, verb, urlParameters, body, options...)
+}
+
+/*
+Prepare a typed request for a given action on a resource bound to the caller context, see Do() and resource.RequestContext().
+Returns a function to make the actual HTTP call, and a request cancelling function that can be used to abort the execution of the first returned function
+*/
+func DoContext[Req any, Resp any](ctx context.Context, resource *resource, verb string, urlParameters interface{}, body *Req, options ...RequestOption) (TypedCallFunc[Resp], context.CancelFunc, error) {
+
+	//A nil typed pointer must not be serialized as an explicit null body
+	var untypedBody interface{}
+	if body != nil {
+		untypedBody = body
+	}
+
+	settings := newRequestOptions(options)
+	actual := resource.override(settings)
+	request, cancel, err := actual.prepare(ctx, verb, urlParameters, untypedBody, settings)
+	if err != nil {
+		return nil, cancel, err
+	}
+
+	return func() (*Resp, int, error) {
+		output := new(Resp)
+		statusCode, err := actual.callInto(request, output)
+		if err != nil {
+			return nil, statusCode, err
+		}
+		return output, statusCode, nil
+	}, cancel, nil
+
+}
@@ -0,0 +1,113 @@
+package resources
+
+import (
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/okayawright/exp_http_client/resources/mocks"
+)
+
+/* Nominal case, send a typed body and get back a typed response */
+func TestDoNominal(t *testing.T) {
+	type secret struct {
+		Token string `json:"token"`
+	}
+	type body struct {
+		Data secret `json:"data"`
+	}
+	save := body{
+		Data: secret{
+			Token: "zufeb5e1b6e1b6eb",
+		},
+	}
+	expectedStatusCode := 201
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	// Echo the request body back
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: expectedStatusCode,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       req.Body,
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := Do[body, body](res, "POST", nil, &save)
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	observed, observedStatusCode, err := call()
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	if observedStatusCode != expectedStatusCode {
+		t.Errorf("Do() = %v, want %v", observedStatusCode, expectedStatusCode)
+	}
+	if !reflect.DeepEqual(*observed, save) {
+		t.Errorf("Do() = %v, want %v", *observed, save)
+	}
+}
+
+/* Nominal case, no request body and an empty response body */
+func TestDoNoBodyNominal(t *testing.T) {
+	type body struct {
+		Token string `json:"token"`
+	}
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			t.Errorf("Do() unexpected request body")
+		}
+		return &http.Response{
+			StatusCode: 204,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := Do[interface{}, body](res, "DELETE", nil, nil)
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	observed, _, err := call()
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(*observed, body{}) {
+		t.Errorf("Do() = %v, want %v", *observed, body{})
+	}
+}
+
+/* Error case, the response body does not match the expected struct */
+func TestDoInvalidResponseError(t *testing.T) {
+	type body struct {
+		Token string `json:"token"`
+	}
+
+	url, _ := netUrl.Parse("http://localhost:8080/api/julien/info")
+	mockClient := mocks.Client{}
+	mockClient.MockedDo = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"token":42}`)),
+		}, nil
+	}
+	res := NewResource(url).WithClient(&mockClient)
+
+	call, _, err := Do[interface{}, body](res, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("Do() unexpected error %v", err)
+	}
+	_, _, err = call()
+	if err == nil {
+		t.Errorf("Do() unexpected success")
+	}
+}
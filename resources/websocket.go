@@ -0,0 +1,63 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/okayawright/exp_http_client/resources/websockets"
+)
+
+/*
+Open a WebSocket connection to this resource, with optional values for named parameters within the URL, see Request().
+The endpoint may use the ws and wss schemes as well as the http and https ones.
+The opening handshake is sent like any other request, through the middlewares, the authentication, the rate limiting and the retrier of the resource, with its HTTP client, which must support the upgrade of the connections like http.Client does.
+The timeouts of the resource only apply to the handshake, the connection then lasts until it is closed, or until ctx is cancelled.
+The messages are (de)serialized with the marshaller of the resource.
+Returns the connection, or an HTTPError if the server refused it with a non-2xx status code
+*/
+func (resource *resource) Dial(ctx context.Context, urlParameters interface{}, options ...RequestOption) (*websockets.Conn, error) {
+	settings := newRequestOptions(options)
+	actual := resource.override(settings)
+	request, cancel, err := actual.prepare(ctx, http.MethodGet, urlParameters, nil, settings)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	switch request.URL.Scheme {
+	case "ws":
+		request.URL.Scheme = "http"
+	case "wss":
+		request.URL.Scheme = "https"
+	}
+	//The messages are not subject to content negotiation
+	request.Header.Del("Accept")
+	key, err := websockets.Upgrade(request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response, tries, err := actual.open(request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if !isSuccessful(response.StatusCode) && response.StatusCode != http.StatusSwitchingProtocols {
+		cancel()
+		return nil, actual.readHTTPError(request, response, tries)
+	}
+	transport, err := websockets.Verify(response, key)
+	if err != nil {
+		response.Body.Close()
+		cancel()
+		return nil, err
+	}
+
+	//The connection is bound to the caller context, even if a write is stuck
+	conn := websockets.NewConn(transport, actual.marshaller).WithContext(request.Context())
+	go func() {
+		<-conn.Done()
+		cancel()
+	}()
+	return conn, nil
+}
@@ -0,0 +1,142 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	netUrl "net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okayawright/exp_http_client/resources/auth"
+	"github.com/okayawright/exp_http_client/resources/websockets"
+)
+
+/* Accept the upgrade of a hijacked connection, then hand it over to serve */
+func upgradeHandler(t *testing.T, serve func(request *http.Request, connection net.Conn, reader *bufio.Reader)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		digest := sha1.Sum([]byte(request.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		connection, buffer, err := writer.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() unexpected error %v", err)
+			return
+		}
+		defer connection.Close()
+		buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		buffer.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(digest[:]) + "\r\n\r\n")
+		buffer.Flush()
+		serve(request, connection, buffer.Reader)
+	}
+}
+
+/* Read a short masked frame sent by the client.
+Returns its opcode and unmasked payload */
+func readClientFrame(reader *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 6)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= header[2+i%4]
+	}
+	return header[0] & 0x0F, payload, nil
+}
+
+/* Nominal case, the handshake goes through the resource configuration, then a message is echoed */
+func TestResourceDialNominal(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	closed := make(chan []byte, 1)
+	server := httptest.NewServer(upgradeHandler(t, func(request *http.Request, connection net.Conn, reader *bufio.Reader) {
+		requests <- request
+		_, payload, err := readClientFrame(reader)
+		if err != nil {
+			t.Errorf("server unexpected error %v", err)
+			return
+		}
+		connection.Write(append([]byte{0x81, byte(len(payload))}, payload...))
+		_, payload, _ = readClientFrame(reader)
+		closed <- payload
+	}))
+	defer server.Close()
+
+	url, _ := netUrl.Parse(strings.Replace(server.URL, "http", "ws", 1) + "/api/{user}/ws")
+	res := NewResource(url).WithAuth(auth.NewBearer("token")).WithTimeout(100 * time.Millisecond)
+
+	conn, err := res.Dial(context.Background(), &map[string]string{"user": "julien"})
+	if err != nil {
+		t.Fatalf("Dial() unexpected error %v", err)
+	}
+	observed := <-requests
+	if observed.URL.Path != "/api/julien/ws" || observed.Header.Get("Authorization") != "Bearer token" || observed.Header.Get("Upgrade") != "websocket" {
+		t.Errorf("Dial() sent %v %v", observed.URL, observed.Header)
+	}
+	//The handshake timeout doesn't apply to the connection
+	time.Sleep(150 * time.Millisecond)
+	if err := conn.Send(map[string]interface{}{"name": "julien"}); err != nil {
+		t.Fatalf("Send() unexpected error %v", err)
+	}
+	message, err := conn.Receive()
+	if err != nil || !reflect.DeepEqual(message, map[string]interface{}{"name": "julien"}) {
+		t.Errorf("Receive() = %v, %v", message, err)
+	}
+	conn.Close()
+	if payload := <-closed; len(payload) < 2 || payload[0] != 0x03 || payload[1] != 0xE8 {
+		t.Errorf("Close() sent %v", payload)
+	}
+}
+
+/* Nominal case, the connection is closed along with the context */
+func TestResourceDialCancelNominal(t *testing.T) {
+	server := httptest.NewServer(upgradeHandler(t, func(request *http.Request, connection net.Conn, reader *bufio.Reader) {
+		readClientFrame(reader)
+	}))
+	defer server.Close()
+	url, _ := netUrl.Parse(server.URL)
+	res := NewResource(url)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := res.Dial(ctx, nil)
+	if err != nil {
+		t.Fatalf("Dial() unexpected error %v", err)
+	}
+	cancel()
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Dial() connection not closed")
+	}
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, websockets.ErrClosed) {
+		t.Errorf("ReadMessage() = %v, want %v", err, websockets.ErrClosed)
+	}
+}
+
+/* Error case, the server refuses the upgrade */
+func TestResourceDialError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/forbidden" {
+			writer.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	url, _ := netUrl.Parse(server.URL + "/forbidden")
+	if _, err := NewResource(url).Dial(context.Background(), nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Dial() = %v, want %v", err, ErrForbidden)
+	}
+	url, _ = netUrl.Parse(server.URL + "/plain")
+	if _, err := NewResource(url).Dial(context.Background(), nil); !errors.Is(err, websockets.ErrHandshake) {
+		t.Errorf("Dial() = %v, want %v", err, websockets.ErrHandshake)
+	}
+}
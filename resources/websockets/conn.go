@@ -0,0 +1,395 @@
+package websockets
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/okayawright/exp_http_client/resources/serializers"
+)
+
+// Sentinel errors, to be used with errors.Is()
+var (
+	//The server refused to upgrade the connection
+	ErrHandshake = errors.New("WebSocket handshake failed")
+	//The peer broke the WebSocket protocol, or a message cannot be sent as such
+	ErrProtocol = errors.New("WebSocket protocol error")
+	//The message received is larger than the maximum size allowed by the connection
+	ErrMessageTooLarge = errors.New("The message is too large")
+	//The connection is closed, by either side
+	ErrClosed = errors.New("The connection is closed")
+	//The peer did not answer the keepalive pings in time
+	ErrKeepAliveTimeout = errors.New("The peer is not responding")
+)
+
+// default maximum size of a message received
+const defaultMaxMessageSize = 32 << 20
+
+// maximum time spent sending our close frame before closing the transport anyway
+const closeTimeout = time.Second
+
+// Status codes of a closing handshake, RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	CloseMessageTooLarge = 1009
+)
+
+/* The connection was closed by the peer, it matches ErrClosed */
+type CloseError struct {
+	//Status code sent by the peer, CloseNoStatus if none
+	Code int
+	//Optional reason sent by the peer
+	Reason string
+}
+
+func (err *CloseError) Error() string {
+	if len(err.Reason) > 0 {
+		return fmt.Sprintf("Connection closed by the peer: %d %s", err.Code, err.Reason)
+	}
+	return fmt.Sprintf("Connection closed by the peer: %d", err.Code)
+}
+
+func (err *CloseError) Unwrap() error {
+	return ErrClosed
+}
+
+/* A message-oriented WebSocket connection, on the client side.
+A single goroutine may read at a time, and the control frames, e.g. the pings of the peer, are only handled while reading, so the connection should be read continuously.
+Writing is safe from several goroutines */
+type Conn struct {
+	//Upgraded HTTP connection
+	transport io.ReadWriteCloser
+	reader    *bufio.Reader
+	//Messages (un)marshaller
+	marshaller serializers.Marshaller
+	//Maximum size of a message received, 0 means no limit but the one of the platform
+	maxMessageSize int64
+	//Only one frame is written at a time
+	writeMutex sync.Mutex
+	//Has our close frame been sent
+	closeSent bool
+	//Has any frame been received since the last keepalive ping, 1 if so
+	alive int32
+	//Closed along with the transport
+	done      chan struct{}
+	closeOnce sync.Once
+	//Why the connection was closed on our side
+	reason error
+}
+
+/* Conn c'tor, for a connection already upgraded, e.g. the body of a 101 Switching Protocols response.
+The messages are (de)serialized with marshaller, JSON by default, and cannot be larger than 32MiB.
+Returns the new connection */
+func NewConn(transport io.ReadWriteCloser, marshaller serializers.Marshaller) *Conn {
+	if marshaller == nil {
+		marshaller = serializers.NewJsonMarshaller()
+	}
+	return &Conn{
+		transport:      transport,
+		reader:         bufio.NewReader(transport),
+		marshaller:     marshaller,
+		maxMessageSize: defaultMaxMessageSize,
+		alive:          1,
+		done:           make(chan struct{}),
+	}
+}
+
+/* Set the maximum size, in bytes, of a message received, 32MiB by default, 0 means no limit but the one of the platform.
+A larger message closes the connection with ErrMessageTooLarge.
+Returns the updated connection */
+func (conn *Conn) WithMaxMessageSize(maxMessageSize int64) *Conn {
+	conn.maxMessageSize = maxMessageSize
+	return conn
+}
+
+/* Ping the peer at every interval, to be called once. The connection is closed with ErrKeepAliveTimeout if nothing was received from the peer in the meantime.
+Returns the updated connection */
+func (conn *Conn) WithKeepAlive(interval time.Duration) *Conn {
+	if interval > 0 {
+		go conn.keepAlive(interval)
+	}
+	return conn
+}
+
+/* Close the connection as soon as ctx is done, without waiting for the pending writes nor notifying the peer.
+Returns the updated connection */
+func (conn *Conn) WithContext(ctx context.Context) *Conn {
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.shutdown(fmt.Errorf("%w: %v", ErrClosed, ctx.Err()))
+		case <-conn.done:
+		}
+	}()
+	return conn
+}
+
+/* Ping the peer until the connection is closed.
+A ping never waits for a pending write, so that a peer which stopped reading is detected as well */
+func (conn *Conn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-conn.done:
+			return
+		case <-ticker.C:
+		}
+		//No point in notifying a peer that doesn't respond
+		if atomic.SwapInt32(&conn.alive, 0) == 0 {
+			conn.shutdown(ErrKeepAliveTimeout)
+			return
+		}
+		go conn.tryWrite(PingMessage, nil)
+	}
+}
+
+/* Closed once the connection is closed, by either side */
+func (conn *Conn) Done() <-chan struct{} {
+	return conn.done
+}
+
+/* Close the transport, once, recording why */
+func (conn *Conn) shutdown(reason error) {
+	conn.closeOnce.Do(func() {
+		conn.reason = reason
+		close(conn.done)
+		conn.transport.Close()
+	})
+}
+
+/* Why the connection was closed on our side, nil if it is still open */
+func (conn *Conn) closed() error {
+	select {
+	case <-conn.done:
+		return conn.reason
+	default:
+		return nil
+	}
+}
+
+/* Close the connection after an error, notifying the peer with a status code if possible.
+Returns the error */
+func (conn *Conn) abort(code int, err error) error {
+	conn.closeWith(code, "", err)
+	return err
+}
+
+/* Send a close frame, unless a write is pending or it takes too long, then close the transport in any case, recording why.
+Returns the error of the close frame, if it could be sent at all */
+func (conn *Conn) closeWith(code int, reason string, cause error) error {
+	written := make(chan error, 1)
+	go func() {
+		written <- conn.tryWriteClose(code, reason)
+	}()
+	var err error
+	timer := time.NewTimer(closeTimeout)
+	select {
+	case err = <-written:
+	case <-timer.C:
+	}
+	timer.Stop()
+	conn.shutdown(cause)
+	if errors.Is(err, errWriteBusy) {
+		return nil
+	}
+	return err
+}
+
+// a frame was not written as another write is pending
+var errWriteBusy = errors.New("A write is pending")
+
+/* Write a single frame, refused once our close frame is sent */
+func (conn *Conn) write(messageType MessageType, payload []byte) error {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+	return conn.writeLocked(messageType, payload)
+}
+
+/* Write a single frame unless another write is pending, in which case errWriteBusy is returned right away */
+func (conn *Conn) tryWrite(messageType MessageType, payload []byte) error {
+	if !conn.writeMutex.TryLock() {
+		return errWriteBusy
+	}
+	defer conn.writeMutex.Unlock()
+	return conn.writeLocked(messageType, payload)
+}
+
+/* Write a single frame, the write lock being held */
+func (conn *Conn) writeLocked(messageType MessageType, payload []byte) error {
+	if conn.closeSent {
+		if reason := conn.closed(); reason != nil {
+			return reason
+		}
+		return ErrClosed
+	}
+	if messageType == CloseMessage {
+		conn.closeSent = true
+	}
+	err := writeFrame(conn.transport, &frame{fin: true, opcode: messageType, masked: true, payload: payload})
+	if reason := conn.closed(); err != nil && reason != nil {
+		return reason
+	}
+	return err
+}
+
+/* Send a close frame, unless already sent or another write is pending */
+func (conn *Conn) tryWriteClose(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatus {
+		if len(reason) > maxControlPayload-2 {
+			reason = reason[:maxControlPayload-2]
+		}
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	return conn.tryWrite(CloseMessage, payload)
+}
+
+/* Send a text or binary message in a single frame */
+func (conn *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("%w: %d is not a data message", ErrProtocol, messageType)
+	}
+	return conn.write(messageType, data)
+}
+
+/* Ping the peer, with an optional payload of up to 125 bytes */
+func (conn *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return fmt.Errorf("%w: ping payload too large", ErrProtocol)
+	}
+	return conn.write(PingMessage, data)
+}
+
+/* Read the next text or binary message, reassembling its fragments, and handle the control frames received meanwhile.
+Returns the type and the payload of the message, a CloseError once the peer closed the connection */
+func (conn *Conn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+	for {
+		received, err := readFrame(conn.reader, conn.maxMessageSize)
+		if errors.Is(err, ErrProtocol) {
+			return 0, nil, conn.abort(CloseProtocolError, err)
+		} else if errors.Is(err, ErrMessageTooLarge) {
+			return 0, nil, conn.abort(CloseMessageTooLarge, err)
+		} else if err != nil {
+			if reason := conn.closed(); reason != nil {
+				return 0, nil, reason
+			}
+			conn.shutdown(ErrClosed)
+			return 0, nil, err
+		}
+		atomic.StoreInt32(&conn.alive, 1)
+		//Only the client masks its frames
+		if received.masked {
+			return 0, nil, conn.abort(CloseProtocolError, fmt.Errorf("%w: masked frame", ErrProtocol))
+		}
+
+		switch received.opcode {
+		case PingMessage:
+			if err := conn.write(PongMessage, received.payload); err != nil {
+				return 0, nil, err
+			}
+		case PongMessage:
+		case CloseMessage:
+			return 0, nil, conn.closedByPeer(received.payload)
+		case TextMessage, BinaryMessage, continuationFrame:
+			if (received.opcode == continuationFrame) != (messageType != 0) {
+				return 0, nil, conn.abort(CloseProtocolError, fmt.Errorf("%w: unexpected fragment", ErrProtocol))
+			}
+			if messageType == 0 {
+				messageType = received.opcode
+			}
+			message = append(message, received.payload...)
+			if conn.maxMessageSize > 0 && int64(len(message)) > conn.maxMessageSize {
+				return 0, nil, conn.abort(CloseMessageTooLarge, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, len(message)))
+			}
+			if received.fin {
+				if messageType == TextMessage && !utf8.Valid(message) {
+					return 0, nil, conn.abort(CloseInvalidPayload, fmt.Errorf("%w: invalid UTF-8 text", ErrProtocol))
+				}
+				return messageType, message, nil
+			}
+		default:
+			return 0, nil, conn.abort(CloseProtocolError, fmt.Errorf("%w: unknown opcode %d", ErrProtocol, received.opcode))
+		}
+	}
+}
+
+/* Answer the close frame of the peer, then close the transport.
+Returns the CloseError of the peer */
+func (conn *Conn) closedByPeer(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	if len(payload) == 1 {
+		return conn.abort(CloseProtocolError, fmt.Errorf("%w: invalid close frame", ErrProtocol))
+	} else if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+	}
+	conn.closeWith(closeErr.Code, "", closeErr)
+	return closeErr
+}
+
+/* Serialize a value with the marshaller of the connection, and send it as a text message, or as a binary one if it is not valid UTF-8 */
+func (conn *Conn) Send(value interface{}) error {
+	payload, err := conn.marshaller.Serialize(value)
+	if err != nil {
+		return err
+	}
+	messageType := BinaryMessage
+	if utf8.Valid(payload) {
+		messageType = TextMessage
+	}
+	return conn.WriteMessage(messageType, payload)
+}
+
+/* Read the next message and deserialize it with the marshaller of the connection.
+Returns the structured message, see ReadMessage() */
+func (conn *Conn) Receive() (interface{}, error) {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return conn.marshaller.Deserialize(message)
+}
+
+/* Read the next message and deserialize it into the given struct pointer with the marshaller of the connection, see ReadMessage() */
+func (conn *Conn) ReceiveInto(output interface{}) error {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return serializers.DeserializeInto(conn.marshaller, message, output)
+}
+
+/* Close the connection with a normal status code, see CloseWithStatus() */
+func (conn *Conn) Close() error {
+	return conn.CloseWithStatus(CloseNormalClosure, "")
+}
+
+/* Send a close frame with the given status code and optional reason, then close the transport without waiting for the answer of the peer.
+The close frame is skipped if another write is pending, e.g. to a peer which stopped reading, and given up after 1s, the transport being closed in any case.
+Closing an already closed connection does nothing */
+func (conn *Conn) CloseWithStatus(code int, reason string) error {
+	if conn.closed() != nil {
+		return nil
+	}
+	err := conn.closeWith(code, reason, ErrClosed)
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
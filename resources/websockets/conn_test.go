@@ -0,0 +1,257 @@
+package websockets
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/* Server side of a connection, sending unmasked frames */
+type peer struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+/* Connect a client to a fake server */
+func newPeers() (*Conn, *peer) {
+	client, server := net.Pipe()
+	return NewConn(client, nil), &peer{conn: server, reader: bufio.NewReader(server)}
+}
+
+func (server *peer) write(fin bool, opcode MessageType, payload []byte) error {
+	return writeFrame(server.conn, &frame{fin: fin, opcode: opcode, payload: payload})
+}
+
+func (server *peer) read(opcode MessageType) (*frame, error) {
+	received, err := readFrame(server.reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !received.masked || received.opcode != opcode {
+		return nil, fmt.Errorf("unexpected frame %v, masked %v", received.opcode, received.masked)
+	}
+	return received, nil
+}
+
+/* Status code of a close frame */
+func closeCode(received *frame) int {
+	if len(received.payload) < 2 {
+		return CloseNoStatus
+	}
+	return int(binary.BigEndian.Uint16(received.payload))
+}
+
+/* Nominal case, a message is echoed back in fragments, with a ping in between, then the server closes the connection */
+func TestConnNominal(t *testing.T) {
+	conn, server := newPeers()
+	failures := make(chan error, 1)
+	go func() {
+		failures <- func() error {
+			received, err := server.read(TextMessage)
+			if err != nil {
+				return err
+			}
+			half := len(received.payload) / 2
+			server.write(false, TextMessage, received.payload[:half])
+			server.write(true, PingMessage, []byte("heartbeat"))
+			if pong, err := server.read(PongMessage); err != nil || string(pong.payload) != "heartbeat" {
+				return fmt.Errorf("pong %v, %v", pong, err)
+			}
+			server.write(true, continuationFrame, received.payload[half:])
+			server.write(true, CloseMessage, append([]byte{0x03, 0xE8}, "bye"...))
+			if answer, err := server.read(CloseMessage); err != nil || closeCode(answer) != CloseNormalClosure {
+				return fmt.Errorf("close %v, %v", answer, err)
+			}
+			return nil
+		}()
+	}()
+
+	if err := conn.Send(map[string]interface{}{"name": "julien"}); err != nil {
+		t.Fatalf("Send() unexpected error %v", err)
+	}
+	observed, err := conn.Receive()
+	if err != nil || !reflect.DeepEqual(observed, map[string]interface{}{"name": "julien"}) {
+		t.Fatalf("Receive() = %v, %v", observed, err)
+	}
+	_, err = conn.Receive()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure || closeErr.Reason != "bye" || !errors.Is(err, ErrClosed) {
+		t.Errorf("Receive() = %v, want a CloseError", err)
+	}
+	if err := <-failures; err != nil {
+		t.Errorf("server: %v", err)
+	}
+	select {
+	case <-conn.Done():
+	default:
+		t.Errorf("Done() is not closed")
+	}
+	if err := conn.Send("late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() = %v, want %v", err, ErrClosed)
+	}
+}
+
+/* Nominal case, the client closes the connection */
+func TestConnCloseNominal(t *testing.T) {
+	conn, server := newPeers()
+	failures := make(chan error, 1)
+	go func() {
+		received, err := server.read(CloseMessage)
+		if err == nil && (closeCode(received) != CloseGoingAway || string(received.payload[2:]) != "leaving") {
+			err = fmt.Errorf("close %v", received.payload)
+		}
+		failures <- err
+	}()
+
+	if err := conn.CloseWithStatus(CloseGoingAway, "leaving"); err != nil {
+		t.Errorf("CloseWithStatus() unexpected error %v", err)
+	}
+	if err := <-failures; err != nil {
+		t.Errorf("server: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close() unexpected error %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("ReadMessage() = %v, want %v", err, ErrClosed)
+	}
+}
+
+/* Error case, the peer stops answering the keepalive pings */
+func TestConnKeepAliveError(t *testing.T) {
+	conn, server := newPeers()
+	go func() {
+		//Answer the first ping only
+		for pings := 0; ; pings++ {
+			received, err := server.read(PingMessage)
+			if err != nil {
+				return
+			}
+			if pings == 0 {
+				server.write(true, PongMessage, received.payload)
+			}
+		}
+	}()
+
+	conn.WithKeepAlive(10 * time.Millisecond)
+	start := time.Now()
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrKeepAliveTimeout) {
+		t.Errorf("ReadMessage() = %v, want %v", err, ErrKeepAliveTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("ReadMessage() failed after %v, too early", elapsed)
+	}
+}
+
+/* Error case, the server breaks the protocol, and the connection is closed with the matching status code */
+func TestConnError(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     func(server *peer) error
+		expected error
+		code     int
+	}{
+		{"masked frame", func(server *peer) error {
+			return writeFrame(server.conn, &frame{fin: true, opcode: TextMessage, masked: true, payload: []byte("{}")})
+		}, ErrProtocol, CloseProtocolError},
+		{"unexpected continuation", func(server *peer) error {
+			return server.write(true, continuationFrame, []byte("{}"))
+		}, ErrProtocol, CloseProtocolError},
+		{"interleaved message", func(server *peer) error {
+			server.write(false, TextMessage, []byte("{"))
+			return server.write(true, TextMessage, []byte("}"))
+		}, ErrProtocol, CloseProtocolError},
+		{"invalid text", func(server *peer) error {
+			return server.write(true, TextMessage, []byte{0xFF, 0xFE})
+		}, ErrProtocol, CloseInvalidPayload},
+		{"too large", func(server *peer) error {
+			server.write(false, BinaryMessage, []byte("0123"))
+			return server.write(true, continuationFrame, []byte("4567"))
+		}, ErrMessageTooLarge, CloseMessageTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, server := newPeers()
+			conn.WithMaxMessageSize(6)
+			codes := make(chan int, 1)
+			go func() {
+				test.send(server)
+				received, err := server.read(CloseMessage)
+				if err != nil {
+					codes <- 0
+					return
+				}
+				codes <- closeCode(received)
+			}()
+			if _, _, err := conn.ReadMessage(); !errors.Is(err, test.expected) {
+				t.Errorf("ReadMessage() = %v, want %v", err, test.expected)
+			}
+			if code := <-codes; code != test.code {
+				t.Errorf("close code = %v, want %v", code, test.code)
+			}
+		})
+	}
+}
+
+/* Error case, the server announces a frame far larger than the default limit, which is refused before being read */
+func TestConnHugeFrameError(t *testing.T) {
+	conn, server := newPeers()
+	codes := make(chan int, 1)
+	go func() {
+		server.conn.Write([]byte{0x82, 0x7F, 0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+		received, err := server.read(CloseMessage)
+		if err != nil {
+			codes <- 0
+			return
+		}
+		codes <- closeCode(received)
+	}()
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("ReadMessage() = %v, want %v", err, ErrMessageTooLarge)
+	}
+	if code := <-codes; code != CloseMessageTooLarge {
+		t.Errorf("close code = %v, want %v", code, CloseMessageTooLarge)
+	}
+}
+
+/* Error case, the peer stops reading while a message is being sent, the connection can still be closed */
+func TestConnBlockedPeerError(t *testing.T) {
+	for _, name := range []string{"close", "context", "keepalive"} {
+		t.Run(name, func(t *testing.T) {
+			conn, _ := newPeers()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			conn.WithContext(ctx)
+			written := make(chan error, 1)
+			go func() {
+				written <- conn.WriteMessage(BinaryMessage, make([]byte, 1<<20))
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			switch name {
+			case "close":
+				if err := conn.Close(); err != nil {
+					t.Errorf("Close() unexpected error %v", err)
+				}
+			case "context":
+				cancel()
+			case "keepalive":
+				conn.WithKeepAlive(10 * time.Millisecond)
+			}
+			select {
+			case <-conn.Done():
+			case <-time.After(500 * time.Millisecond):
+				t.Fatalf("connection not closed")
+			}
+			if err := <-written; !errors.Is(err, ErrClosed) && !errors.Is(err, ErrKeepAliveTimeout) {
+				t.Errorf("WriteMessage() = %v, want %v", err, ErrClosed)
+			}
+		})
+	}
+}
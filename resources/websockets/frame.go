@@ -0,0 +1,161 @@
+package websockets
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+/* Opcode of a frame, RFC 6455 section 5.2 */
+type MessageType byte
+
+const (
+	//Continuation of a fragmented message
+	continuationFrame MessageType = 0x0
+	//UTF-8 text message
+	TextMessage MessageType = 0x1
+	//Binary message
+	BinaryMessage MessageType = 0x2
+	//Closing handshake, with an optional status code and reason
+	CloseMessage MessageType = 0x8
+	//Keepalive request, to be answered with a pong carrying the same payload
+	PingMessage MessageType = 0x9
+	//Keepalive answer
+	PongMessage MessageType = 0xA
+)
+
+// maximum payload length of a control frame
+const maxControlPayload = 125
+
+/* Is it a control frame, i.e. close, ping or pong */
+func (messageType MessageType) isControl() bool {
+	return messageType&0x8 != 0
+}
+
+/* A single WebSocket frame */
+type frame struct {
+	//Is it the last fragment of its message
+	fin    bool
+	opcode MessageType
+	//Was the payload masked, as it must be by a client, never by a server
+	masked bool
+	//Unmasked payload
+	payload []byte
+}
+
+/* Read the next frame, unmasking its payload if needed.
+maxSize is the maximum length of the payload, 0 means no limit but the one of the platform.
+The payload is read as it comes, its announced length is never allocated upfront.
+Returns ErrProtocol if the frame is malformed, ErrMessageTooLarge if it is too large */
+func readFrame(reader *bufio.Reader, maxSize int64) (*frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0]&0x70 != 0 {
+		return nil, fmt.Errorf("%w: reserved bits set without extension", ErrProtocol)
+	}
+	result := &frame{
+		fin:    header[0]&0x80 != 0,
+		opcode: MessageType(header[0] & 0x0F),
+		masked: header[1]&0x80 != 0,
+	}
+
+	//The length is either given right away, or on the next 2 or 8 bytes
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(reader, extended[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(reader, extended[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+		if length>>63 != 0 {
+			return nil, fmt.Errorf("%w: invalid payload length", ErrProtocol)
+		}
+	}
+	if result.opcode.isControl() && (length > maxControlPayload || !result.fin) {
+		return nil, fmt.Errorf("%w: invalid control frame", ErrProtocol)
+	}
+	if (maxSize > 0 && length > uint64(maxSize)) || length > math.MaxInt {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, length)
+	}
+
+	var mask [4]byte
+	if result.masked {
+		if _, err := io.ReadFull(reader, mask[:]); err != nil {
+			return nil, err
+		}
+	}
+	//The peer may announce much more than it actually sends
+	payload, err := io.ReadAll(io.LimitReader(reader, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(payload)) < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	result.payload = payload
+	if result.masked {
+		maskBytes(mask, result.payload)
+	}
+	return result, nil
+}
+
+/* Write a frame in a single call, masking its payload with a random key if required */
+func writeFrame(writer io.Writer, toWrite *frame) error {
+	buffer := make([]byte, 0, 14+len(toWrite.payload))
+	first := byte(toWrite.opcode)
+	if toWrite.fin {
+		first |= 0x80
+	}
+	buffer = append(buffer, first)
+
+	var maskBit byte
+	if toWrite.masked {
+		maskBit = 0x80
+	}
+	length := len(toWrite.payload)
+	switch {
+	case length < 126:
+		buffer = append(buffer, maskBit|byte(length))
+	case length <= 0xFFFF:
+		buffer = append(buffer, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(buffer[len(buffer)-2:], uint16(length))
+	default:
+		buffer = append(buffer, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buffer[len(buffer)-8:], uint64(length))
+	}
+
+	if toWrite.masked {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buffer = append(buffer, mask[:]...)
+		start := len(buffer)
+		buffer = append(buffer, toWrite.payload...)
+		maskBytes(mask, buffer[start:])
+	} else {
+		buffer = append(buffer, toWrite.payload...)
+	}
+
+	_, err := writer.Write(buffer)
+	return err
+}
+
+/* (Un)mask the payload in place, the operation is its own inverse */
+func maskBytes(mask [4]byte, payload []byte) {
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+}
@@ -0,0 +1,57 @@
+package websockets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+/* Nominal case, frames of every length encoding are read back as written */
+func TestFrameNominal(t *testing.T) {
+	for _, length := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		for _, masked := range []bool{false, true} {
+			payload := bytes.Repeat([]byte("a"), length)
+			var buffer bytes.Buffer
+			if err := writeFrame(&buffer, &frame{fin: true, opcode: BinaryMessage, masked: masked, payload: payload}); err != nil {
+				t.Fatalf("writeFrame() unexpected error %v", err)
+			}
+			if masked && length > 0 && bytes.Contains(buffer.Bytes(), payload) {
+				t.Errorf("writeFrame() did not mask the payload")
+			}
+			observed, err := readFrame(bufio.NewReader(&buffer), 0)
+			if err != nil {
+				t.Fatalf("readFrame() unexpected error %v", err)
+			}
+			if !observed.fin || observed.opcode != BinaryMessage || observed.masked != masked || !bytes.Equal(observed.payload, payload) {
+				t.Errorf("readFrame() = %v, %v, %v, %v bytes", observed.fin, observed.opcode, observed.masked, len(observed.payload))
+			}
+		}
+	}
+}
+
+/* Error case, malformed or too large frames */
+func TestFrameError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		maxSize  int64
+		expected error
+	}{
+		{"reserved bits", []byte{0xC1, 0x00}, 0, ErrProtocol},
+		{"fragmented control frame", []byte{0x09, 0x00}, 0, ErrProtocol},
+		{"control frame too long", append([]byte{0x89, 0x7E, 0x00, 0x7E}, make([]byte, 126)...), 0, ErrProtocol},
+		{"too large", append([]byte{0x82, 0x05}, []byte("hello")...), 4, ErrMessageTooLarge},
+		{"huge announced length", []byte{0x82, 0x7F, 0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, defaultMaxMessageSize, ErrMessageTooLarge},
+		{"huge announced length without limit", []byte{0x82, 0x7F, 0x3F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, 0, io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readFrame(bufio.NewReader(bytes.NewReader(test.input)), test.maxSize)
+			if !errors.Is(err, test.expected) {
+				t.Errorf("readFrame() = %v, want %v", err, test.expected)
+			}
+		})
+	}
+}
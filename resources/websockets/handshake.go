@@ -0,0 +1,67 @@
+package websockets
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// magic value appended to the key of the handshake, RFC 6455 section 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+/* Augment a GET request with the headers of an opening handshake.
+Returns the generated key of the handshake, to be checked against the response with Verify() */
+func Upgrade(request *http.Request) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	return key, nil
+}
+
+/* Expected Sec-WebSocket-Accept value of the response for a given key */
+func acceptKey(key string) string {
+	digest := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+/* Does a comma-separated header contain a token, case-insensitively */
+func hasToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, candidate := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(candidate), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* Check the response to an opening handshake sent with the given key.
+The response body is left untouched, it is up to the caller to close it on failure.
+Returns the upgraded connection, or ErrHandshake if the server did not accept the upgrade */
+func Verify(response *http.Response, key string) (io.ReadWriteCloser, error) {
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrHandshake, response.StatusCode)
+	}
+	if !hasToken(response.Header, "Upgrade", "websocket") || !hasToken(response.Header, "Connection", "upgrade") {
+		return nil, fmt.Errorf("%w: missing upgrade headers", ErrHandshake)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Accept", ErrHandshake)
+	}
+	connection, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		return nil, fmt.Errorf("%w: the HTTP client does not support upgrades", ErrHandshake)
+	}
+	return connection, nil
+}
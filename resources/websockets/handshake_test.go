@@ -0,0 +1,70 @@
+package websockets
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+/* Nominal case, the example of RFC 6455 */
+func TestAcceptKeyNominal(t *testing.T) {
+	if observed := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); observed != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("acceptKey() = %v", observed)
+	}
+}
+
+/* Nominal case, the upgrade is accepted */
+func TestVerifyNominal(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/api/julien/ws", nil)
+	key, err := Upgrade(request)
+	if err != nil || len(key) != 24 || request.Header.Get("Sec-WebSocket-Key") != key || request.Header.Get("Upgrade") != "websocket" {
+		t.Fatalf("Upgrade() = %v, %v, %v", key, request.Header, err)
+	}
+
+	client, server := net.Pipe()
+	defer server.Close()
+	response := &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		Header: http.Header{
+			"Upgrade":              []string{"WebSocket"},
+			"Connection":           []string{"keep-alive, Upgrade"},
+			"Sec-Websocket-Accept": []string{acceptKey(key)},
+		},
+		Body: client,
+	}
+	connection, err := Verify(response, key)
+	if err != nil || connection != client {
+		t.Errorf("Verify() = %v, %v", connection, err)
+	}
+}
+
+/* Error case, the upgrade is refused */
+func TestVerifyError(t *testing.T) {
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	valid := http.Header{
+		"Upgrade":              []string{"websocket"},
+		"Connection":           []string{"Upgrade"},
+		"Sec-Websocket-Accept": []string{acceptKey(key)},
+	}
+	client, server := net.Pipe()
+	defer server.Close()
+	tests := []struct {
+		name     string
+		response *http.Response
+	}{
+		{"status", &http.Response{StatusCode: http.StatusOK, Header: valid, Body: client}},
+		{"headers", &http.Response{StatusCode: http.StatusSwitchingProtocols, Header: http.Header{"Sec-Websocket-Accept": []string{acceptKey(key)}}, Body: client}},
+		{"accept", &http.Response{StatusCode: http.StatusSwitchingProtocols, Header: http.Header{"Upgrade": []string{"websocket"}, "Connection": []string{"Upgrade"}, "Sec-Websocket-Accept": []string{key}}, Body: client}},
+		{"not writable", &http.Response{StatusCode: http.StatusSwitchingProtocols, Header: valid, Body: io.NopCloser(strings.NewReader(""))}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Verify(test.response, key); !errors.Is(err, ErrHandshake) {
+				t.Errorf("Verify() = %v, want %v", err, ErrHandshake)
+			}
+		})
+	}
+}